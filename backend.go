@@ -1,6 +1,12 @@
 package balancer
 
 import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -13,8 +19,14 @@ type Backends struct {
 	sync.RWMutex
 	nodes    []*Backend
 	nodesMap map[string]*Backend
+	version  uint64
+	watchers []*BackendsWatcher
+	watchMux sync.RWMutex
 }
 
+// BackendsWatcher is called after a backend is added or deleted.
+type BackendsWatcher func(backend *Backend, added bool)
+
 // NewBackends create a list of backend nodes
 func NewBackends() *Backends {
 	return &Backends{
@@ -26,15 +38,61 @@ func NewBackends() *Backends {
 // Add add backend node
 func (b *Backends) Add(newnode *Backend) bool {
 	b.Lock()
-	defer b.Unlock()
-	return b.add(newnode)
+	ok := b.add(newnode)
+	b.Unlock()
+
+	if ok {
+		b.notify(newnode, true)
+	}
+	return ok
 }
 
 // Delete delete backend node
 func (b *Backends) Delete(newnode *Backend) bool {
 	b.Lock()
-	defer b.Unlock()
-	return b.delete(newnode)
+	ok := b.delete(newnode)
+	b.Unlock()
+
+	if ok {
+		b.notify(newnode, false)
+	}
+	return ok
+}
+
+// Watch adds a watcher of the added and deleted backends, the watcher is called without the lock of the backends.
+// The returned unwatch removes the watcher, the watcher is not called any more after unwatch returns.
+func (b *Backends) Watch(watcher BackendsWatcher) (unwatch func()) {
+	w := &watcher
+
+	b.watchMux.Lock()
+	defer b.watchMux.Unlock()
+	b.watchers = append(b.watchers, w)
+
+	return func() {
+		b.watchMux.Lock()
+		defer b.watchMux.Unlock()
+		for i, watcher := range b.watchers {
+			if watcher == w {
+				b.watchers = append(b.watchers[:i:i], b.watchers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (b *Backends) notify(backend *Backend, added bool) {
+	if added {
+		backend.Emit(EventBackendAdded, "")
+	} else {
+		backend.Emit(EventBackendRemoved, "")
+	}
+
+	b.watchMux.RLock()
+	defer b.watchMux.RUnlock()
+
+	for _, watcher := range b.watchers {
+		(*watcher)(backend, added)
+	}
 }
 
 // Get get the backend node by index and name
@@ -51,6 +109,11 @@ func (b *Backends) Len() int {
 	return len(b.nodes)
 }
 
+// Version return the version of the backend nodes, it changes when a node is added or deleted
+func (b *Backends) Version() uint64 {
+	return atomic.LoadUint64(&b.version)
+}
+
 // Range traverse back-end nodes
 func (b *Backends) Range(f func(index int, backend *Backend) bool) {
 	b.RLock()
@@ -74,6 +137,7 @@ func (b *Backends) add(newnode *Backend) bool {
 
 	b.nodesMap[newnode.URL] = newnode
 	b.nodes = append(b.nodes, newnode)
+	atomic.AddUint64(&b.version, 1)
 	return true
 }
 
@@ -91,6 +155,7 @@ func (b *Backends) delete(newnode *Backend) bool {
 	for i, node := range b.nodes {
 		if node.URL == newnode.URL {
 			b.nodes = append(b.nodes[:i], b.nodes[i+1:]...)
+			atomic.AddUint64(&b.version, 1)
 			return true
 		}
 	}
@@ -119,33 +184,292 @@ type Backend struct {
 	State     *State
 	Statistic *Statistic
 	Cache     *common.Cache
+	Breaker   *CircuitBreaker
+	balancer  string
+	slowStart SlowStartOptions
+	warmup    int64
+	removing  int32
+	weight    int64
+	inflight  int64
+	target    *url.URL
+	tags      map[string]struct{}
+	header    http.Header
+	mux       sync.RWMutex
 }
 
 // NewBackend creates a Backend.
 func NewBackend(url string, cacheSize int) *Backend {
-	return &Backend{
-		URL: url,
-		State: &State{
-			alive: true,
-		},
+	backend := &Backend{
+		URL:       url,
+		State:     NewState(DefaultFailureWindow*time.Second, DefaultFailureRing),
 		Statistic: &Statistic{},
 		Cache:     common.NewCache(cacheSize),
+		weight:    1,
+		target:    parseTarget(url),
+		header:    make(http.Header),
+	}
+	backend.State.notify = backend.Emit
+	return backend
+}
+
+// parseTarget parses the node url, the scheme is http if it is not specified.
+func parseTarget(rawurl string) *url.URL {
+	if !strings.HasPrefix(rawurl, "http://") && !strings.HasPrefix(rawurl, "https://") {
+		rawurl = "http://" + rawurl
+	}
+	target, err := url.Parse(rawurl)
+	if err != nil {
+		return &url.URL{Scheme: "http", Host: strings.TrimPrefix(rawurl, "http://")}
 	}
+	return target
+}
+
+// NewBackendWithOptions creates a Backend with the node settings.
+func NewBackendWithOptions(node BackendOptions, opts *Options) *Backend {
+	backend := NewBackend(node.URL, opts.CacheSize)
+	backend.balancer = opts.Name
+	backend.slowStart = opts.SlowStart
+	backend.State = NewState(opts.Doctor.window(), opts.Doctor.ringSize())
+	backend.State.notify = backend.Emit
+	backend.update(node)
+	if opts.Doctor.Breaker.Enable {
+		backend.Breaker = NewCircuitBreaker(opts.Doctor.Breaker)
+		backend.Breaker.notify = backend.Emit
+	}
+	return backend
+}
+
+// Emit publishes an event of the node to Events, the node warms up again when it is re-admitted
+func (b *Backend) Emit(typ EventType, reason string) {
+	switch typ {
+	case EventBackendAlive, EventBackendReadmitted, EventCircuitClosed:
+		b.Warmup()
+	case EventBackendEjected:
+		b.Statistic.IncEjections()
+	}
+
+	Events.Publish(Event{
+		Type:     typ,
+		Balancer: b.balancer,
+		Backend:  b.URL,
+		Reason:   reason,
+	})
+}
+
+// Warmup starts the warm-up of the node if the slow start is enabled
+func (b *Backend) Warmup() {
+	if b.slowStart.Enable {
+		atomic.StoreInt64(&b.warmup, time.Now().UnixNano())
+	}
+}
+
+// WarmupFactor return the share of traffic of the node during the warm-up, from 0 to 1, 1 if it is warm
+func (b *Backend) WarmupFactor() float64 {
+	start := atomic.LoadInt64(&b.warmup)
+	if start == 0 {
+		return 1
+	}
+
+	factor := b.slowStart.factor(time.Since(time.Unix(0, start)))
+	if factor >= 1 {
+		// the warm-up is over
+		atomic.CompareAndSwapInt64(&b.warmup, start, 0)
+	}
+	return factor
+}
+
+// WaitIdle waits until there is no request in flight to the node, false if it times out
+func (b *Backend) WaitIdle(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for b.InFlight() > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
+}
+
+// startRemoval marks the node as being removed, false if it is already being removed
+func (b *Backend) startRemoval() bool {
+	return atomic.CompareAndSwapInt32(&b.removing, 0, 1)
+}
+
+// cancelRemoval clears the removal mark, false if the node is not being removed
+func (b *Backend) cancelRemoval() bool {
+	return atomic.CompareAndSwapInt32(&b.removing, 1, 0)
+}
+
+// Available if the node is alive, not ejected, not draining and its circuit breaker is not open, Available returns true
+func (b *Backend) Available() bool {
+	if !b.State.Alive() || b.State.Ejected() || b.State.Draining() {
+		return false
+	}
+	return b.Breaker == nil || b.Breaker.Ready()
+}
+
+// Weight return the weight of the node
+func (b *Backend) Weight() int {
+	return int(atomic.LoadInt64(&b.weight))
+}
+
+// SetWeight set the weight of the node, a weight less than 1 is treated as 1
+func (b *Backend) SetWeight(weight int) {
+	if weight < 1 {
+		weight = 1
+	}
+	atomic.StoreInt64(&b.weight, int64(weight))
+}
+
+// Target return the parsed url of the node, the caller must not modify it
+func (b *Backend) Target() *url.URL {
+	return b.target
+}
+
+// Header return the headers added to every request sent to the node
+func (b *Backend) Header() http.Header {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+	return b.header.Clone()
+}
+
+// SetHeader set the headers added to every request sent to the node
+func (b *Backend) SetHeader(header http.Header) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.header = header.Clone()
+}
+
+// Tags return the tags of the node
+func (b *Backend) Tags() []string {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+	tags := make([]string, 0, len(b.tags))
+	for tag := range b.tags {
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// SetTags set the tags of the node
+func (b *Backend) SetTags(tags ...string) {
+	m := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		m[tag] = struct{}{}
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.tags = m
+}
+
+// HasTags if the node has all the tags, HasTags returns true
+func (b *Backend) HasTags(tags ...string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+	for _, tag := range tags {
+		if _, ok := b.tags[tag]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// InFlight return number of requests that have been sent to the node but not finished
+func (b *Backend) InFlight() int64 {
+	return atomic.LoadInt64(&b.inflight)
+}
+
+// IncInFlight auto-increment in-flight requests
+func (b *Backend) IncInFlight() int64 {
+	return atomic.AddInt64(&b.inflight, 1)
+}
+
+// DecInFlight auto-decrement in-flight requests
+func (b *Backend) DecInFlight() int64 {
+	return atomic.AddInt64(&b.inflight, -1)
+}
+
+// update apply the node settings that can be changed at runtime
+func (b *Backend) update(node BackendOptions) {
+	b.SetWeight(node.Weight)
+	b.SetTags(node.Tags...)
+
+	header := make(http.Header)
+	for k, v := range node.Headers {
+		header.Set(k, v)
+	}
+	if len(node.Username) > 0 || len(node.Password) > 0 {
+		auth := base64.StdEncoding.EncodeToString([]byte(node.Username + ":" + node.Password))
+		header.Set("Authorization", "Basic "+auth)
+	}
+	b.SetHeader(header)
 }
 
 // State describes the status information of the node
 type State struct {
-	alive       bool
-	aliveMux    sync.RWMutex
-	failures    []*FailureError
-	failuresMux sync.RWMutex
+	alive        bool
+	held         bool
+	aliveMux     sync.RWMutex
+	window       *SlidingWindow
+	failures     []*FailureError
+	next         int
+	failuresMux  sync.RWMutex
+	ejectedUntil time.Time
+	ejectedMux   sync.RWMutex
+	probes       int
+	probeMux     sync.Mutex
+	draining     int32
+	notify       func(typ EventType, reason string)
+}
+
+// NewState creates an alive State, which counts the requests in the window
+// and keeps the last ringSize failed errors.
+func NewState(window time.Duration, ringSize int) *State {
+	if ringSize <= 0 {
+		ringSize = DefaultFailureRing
+	}
+	return &State{
+		alive:    true,
+		window:   NewSlidingWindow(window, 60),
+		failures: make([]*FailureError, 0, ringSize),
+	}
 }
 
 // SetAlive set whether the node is available
 func (s *State) SetAlive(alive bool) {
+	s.SetAliveReason(alive, "")
+}
+
+// SetAliveReason set whether the node is available, the reason is sent with the event of the change,
+// a node held by Hold is not made alive.
+func (s *State) SetAliveReason(alive bool, reason string) {
 	s.aliveMux.Lock()
-	defer s.aliveMux.Unlock()
+	if alive && s.held {
+		s.aliveMux.Unlock()
+		return
+	}
+	changed := alive != s.alive
+	if alive && !s.alive {
+		// the failures before the node is dead do not count any more
+		s.window.Reset()
+	}
 	s.alive = alive
+	s.aliveMux.Unlock()
+
+	if changed {
+		// the consecutive probes before the change do not count any more
+		s.probeMux.Lock()
+		s.probes = 0
+		s.probeMux.Unlock()
+	}
+	if changed && alive {
+		s.emit(EventBackendAlive, reason)
+	} else if changed {
+		s.emit(EventBackendDead, reason)
+	}
 }
 
 // Alive if the backend is still alive, Alive returns true
@@ -155,55 +479,182 @@ func (s *State) Alive() bool {
 	return s.alive
 }
 
-// AddFail add failed error
-func (s *State) AddFail(err error) {
-	s.failuresMux.Lock()
-	defer s.failuresMux.Unlock()
-	n := len(s.failures)
-	if n == 100 {
-		s.failures = s.failures[1:]
+// Hold marks the node as dead and keeps it dead until Unhold, the doctors cannot make it alive.
+func (s *State) Hold(reason string) {
+	s.aliveMux.Lock()
+	s.held = true
+	s.aliveMux.Unlock()
+	s.SetAliveReason(false, reason)
+}
+
+// Unhold releases the node held by Hold and marks it as alive.
+func (s *State) Unhold(reason string) {
+	s.aliveMux.Lock()
+	s.held = false
+	s.aliveMux.Unlock()
+	s.SetAliveReason(true, reason)
+}
+
+// Held returns whether the node is kept dead by Hold.
+func (s *State) Held() bool {
+	s.aliveMux.RLock()
+	defer s.aliveMux.RUnlock()
+	return s.held
+}
+
+// Eject eject the node from the load balancing until the time
+func (s *State) Eject(until time.Time) {
+	s.EjectReason(until, "")
+}
+
+// EjectReason eject the node until the time, the reason is sent with the event
+func (s *State) EjectReason(until time.Time, reason string) {
+	s.ejectedMux.Lock()
+	s.ejectedUntil = until
+	s.ejectedMux.Unlock()
+
+	s.emit(EventBackendEjected, reason)
+}
+
+// Readmit readmit the ejected node
+func (s *State) Readmit() {
+	s.ejectedMux.Lock()
+	ejected := !s.ejectedUntil.IsZero()
+	s.ejectedUntil = time.Time{}
+	s.ejectedMux.Unlock()
+
+	if ejected {
+		s.emit(EventBackendReadmitted, "")
+	}
+}
+
+func (s *State) emit(typ EventType, reason string) {
+	if s.notify != nil {
+		s.notify(typ, reason)
+	}
+}
+
+// Ejected if the node is ejected by the outlier detection, Ejected returns true
+func (s *State) Ejected() bool {
+	s.ejectedMux.RLock()
+	defer s.ejectedMux.RUnlock()
+	return !s.ejectedUntil.IsZero()
+}
+
+// EjectedUntil return the time until which the node is ejected, zero if the node is not ejected
+func (s *State) EjectedUntil() time.Time {
+	s.ejectedMux.RLock()
+	defer s.ejectedMux.RUnlock()
+	return s.ejectedUntil
+}
+
+// SetDraining set whether the node is draining, a draining node is not picked for new requests
+func (s *State) SetDraining(draining bool) {
+	var val int32
+	if draining {
+		val = 1
 	}
-	s.failures = append(s.failures, NewFailureError(err))
+	atomic.StoreInt32(&s.draining, val)
+}
+
+// Draining if the node is draining, Draining returns true
+func (s *State) Draining() bool {
+	return atomic.LoadInt32(&s.draining) == 1
+}
+
+// AddProbe add the result of a health check ping, and return the number of consecutive
+// results of the same kind, e.g. 3 means the last 3 pings are all successful or all failed
+func (s *State) AddProbe(success bool) int {
+	s.probeMux.Lock()
+	defer s.probeMux.Unlock()
+	if success != (s.probes > 0) {
+		s.probes = 0
+	}
+	if success {
+		s.probes++
+		return s.probes
+	}
+	s.probes--
+	return -s.probes
+}
+
+// AddSuccess add a successful request
+func (s *State) AddSuccess() {
+	s.window.Add(true)
 }
 
-// CleanFail clear outdated errors
-func (s *State) CleanFail(interval int64) {
+// AddFail add failed error
+func (s *State) AddFail(err error) {
+	s.window.Add(false)
+
 	s.failuresMux.Lock()
 	defer s.failuresMux.Unlock()
-	now := time.Now().Unix()
-	for _, v := range s.failures {
-		if now-v.timestamp >= interval {
-			s.failures = s.failures[1:]
-		} else {
-			break
-		}
+	if len(s.failures) < cap(s.failures) {
+		s.failures = append(s.failures, NewFailureError(err))
+		return
 	}
+	s.failures[s.next] = NewFailureError(err)
+	s.next = (s.next + 1) % len(s.failures)
 }
 
-// LenFail return number of failures
-func (s *State) LenFail() int {
+// Failures return the last failed errors, the oldest first
+func (s *State) Failures() []*FailureError {
 	s.failuresMux.RLock()
 	defer s.failuresMux.RUnlock()
-	return len(s.failures)
+	failures := make([]*FailureError, 0, len(s.failures))
+	failures = append(failures, s.failures[s.next:]...)
+	return append(failures, s.failures[:s.next]...)
 }
 
-// HealthCheck determine whether the node is available
-func (s *State) HealthCheck(interval int64, max int) bool {
-	s.CleanFail(interval)
-	length := s.LenFail()
-	if length >= max {
-		s.SetAlive(false)
+// Counts return number of successes and failures in the window
+func (s *State) Counts() (success, failure uint64) {
+	return s.window.Counts()
+}
+
+// LenFail return number of failures in the window
+func (s *State) LenFail() int {
+	_, failure := s.window.Counts()
+	return int(failure)
+}
+
+// HealthCheck determine whether the node is available by the failures in the window,
+// the node is marked as dead if it fails too many times or the failure ratio is too high
+func (s *State) HealthCheck(policy FailurePolicy) bool {
+	success, failure := s.window.Counts()
+	if policy.Unhealthy(success, failure) {
+		s.SetAliveReason(false, fmt.Sprintf("%d successes and %d failures in the window", success, failure))
 		return false
 	}
 	return true
 }
 
+// FailurePolicy decides whether a node is unhealthy by its successes and failures in the window.
+type FailurePolicy struct {
+	MaxFailures  int     //Failures to mark the node as dead, ignored in ratio mode
+	FailureRatio float64 //Ratio mode if it is greater than 0
+	MinRequests  int     //Minimum requests to check the failure ratio
+}
+
+// Unhealthy if the successes and failures exceed the policy, Unhealthy returns true
+func (p FailurePolicy) Unhealthy(success, failure uint64) bool {
+	if p.FailureRatio > 0 {
+		total := success + failure
+		return total > 0 && total >= uint64(p.MinRequests) && float64(failure) >= p.FailureRatio*float64(total)
+	}
+	return failure >= uint64(p.MaxFailures)
+}
+
 // FailureError describe the failed error
 type FailureError struct {
 	timestamp int64
 	err       error
 }
 
+// Timestamp return the unix time of the failure
+func (f *FailureError) Timestamp() int64 {
+	return f.timestamp
+}
+
 // NewFailureError creates a fail error.
 func NewFailureError(err error) *FailureError {
 	return &FailureError{
@@ -219,8 +670,19 @@ func (f *FailureError) Error() string {
 
 // Statistic describe statistics
 type Statistic struct {
-	success uint64
-	failure uint64
+	success           uint64
+	failure           uint64
+	consecutiveErrors uint64
+	statuses          [6]uint64 // errors without response, 1xx, 2xx, 3xx, 4xx and 5xx
+	retries           uint64
+	ejections         uint64
+	probeSuccess      uint64
+	probeFailure      uint64
+	bytesSent         uint64
+	bytesReceived     uint64
+	latency           peakEWMA
+	histogram         Histogram
+	window            RollingWindow
 }
 
 // Success return number of success
@@ -242,3 +704,181 @@ func (s *Statistic) Failure() uint64 {
 func (s *Statistic) IncFailure() uint64 {
 	return atomic.AddUint64(&s.failure, 1)
 }
+
+// ConsecutiveErrors return number of consecutive 5xx responses and errors
+func (s *Statistic) ConsecutiveErrors() uint64 {
+	return atomic.LoadUint64(&s.consecutiveErrors)
+}
+
+// IncConsecutiveErrors auto-increment consecutive errors
+func (s *Statistic) IncConsecutiveErrors() uint64 {
+	return atomic.AddUint64(&s.consecutiveErrors, 1)
+}
+
+// ResetConsecutiveErrors reset consecutive errors after a successful response
+func (s *Statistic) ResetConsecutiveErrors() {
+	atomic.StoreUint64(&s.consecutiveErrors, 0)
+}
+
+// IncStatus auto-increment the requests of the status class, status 0 is an error without response
+func (s *Statistic) IncStatus(status int) uint64 {
+	class := status / 100
+	if class < 0 || class >= len(s.statuses) {
+		class = 0
+	}
+	s.window.Add(class == 0 || class >= 4)
+	return atomic.AddUint64(&s.statuses[class], 1)
+}
+
+// Statuses return number of requests by status class, the index is the class, 0 is the errors without response
+func (s *Statistic) Statuses() []uint64 {
+	statuses := make([]uint64, len(s.statuses))
+	for i := range s.statuses {
+		statuses[i] = atomic.LoadUint64(&s.statuses[i])
+	}
+	return statuses
+}
+
+// Retries return number of retried requests sent to the node
+func (s *Statistic) Retries() uint64 {
+	return atomic.LoadUint64(&s.retries)
+}
+
+// IncRetries auto-increment retried requests
+func (s *Statistic) IncRetries() uint64 {
+	return atomic.AddUint64(&s.retries, 1)
+}
+
+// Ejections return number of ejections by the outlier detection
+func (s *Statistic) Ejections() uint64 {
+	return atomic.LoadUint64(&s.ejections)
+}
+
+// IncEjections auto-increment ejections
+func (s *Statistic) IncEjections() uint64 {
+	return atomic.AddUint64(&s.ejections, 1)
+}
+
+// Probes return number of successful and failed pings of the doctor
+func (s *Statistic) Probes() (success, failure uint64) {
+	return atomic.LoadUint64(&s.probeSuccess), atomic.LoadUint64(&s.probeFailure)
+}
+
+// IncProbe auto-increment successful or failed pings
+func (s *Statistic) IncProbe(success bool) uint64 {
+	if success {
+		return atomic.AddUint64(&s.probeSuccess, 1)
+	}
+	return atomic.AddUint64(&s.probeFailure, 1)
+}
+
+// ObserveLatency record the latency of a request
+func (s *Statistic) ObserveLatency(rtt time.Duration) {
+	s.latency.observe(rtt)
+	s.histogram.Observe(rtt)
+	s.window.Observe(rtt)
+}
+
+// Reset reset all the statistics
+func (s *Statistic) Reset() {
+	atomic.StoreUint64(&s.success, 0)
+	atomic.StoreUint64(&s.failure, 0)
+	atomic.StoreUint64(&s.consecutiveErrors, 0)
+	for i := range s.statuses {
+		atomic.StoreUint64(&s.statuses[i], 0)
+	}
+	atomic.StoreUint64(&s.retries, 0)
+	atomic.StoreUint64(&s.ejections, 0)
+	atomic.StoreUint64(&s.probeSuccess, 0)
+	atomic.StoreUint64(&s.probeFailure, 0)
+	atomic.StoreUint64(&s.bytesSent, 0)
+	atomic.StoreUint64(&s.bytesReceived, 0)
+	s.latency.reset()
+	s.histogram.Reset()
+	s.window.Reset()
+}
+
+// Histogram return the latency histogram
+func (s *Statistic) Histogram() *Histogram {
+	return &s.histogram
+}
+
+// Window return the statistics of the requests in the last minutes, from 1 to 15
+func (s *Statistic) Window(minutes int) WindowStats {
+	return s.window.Stats(minutes)
+}
+
+// BytesSent return number of request body bytes sent to the node
+func (s *Statistic) BytesSent() uint64 {
+	return atomic.LoadUint64(&s.bytesSent)
+}
+
+// AddBytesSent add request body bytes sent to the node
+func (s *Statistic) AddBytesSent(n int) uint64 {
+	return atomic.AddUint64(&s.bytesSent, uint64(n))
+}
+
+// BytesReceived return number of response body bytes received from the node
+func (s *Statistic) BytesReceived() uint64 {
+	return atomic.LoadUint64(&s.bytesReceived)
+}
+
+// AddBytesReceived add response body bytes received from the node
+func (s *Statistic) AddBytesReceived(n int) uint64 {
+	return atomic.AddUint64(&s.bytesReceived, uint64(n))
+}
+
+// Latency return the decayed peak-EWMA latency, ok is false if there is no sample yet
+func (s *Statistic) Latency() (latency time.Duration, ok bool) {
+	return s.latency.value()
+}
+
+// LatencyDecay is the time constant of the latency peak-EWMA.
+const LatencyDecay = 10 * time.Second
+
+// peakEWMA is an exponentially weighted moving average that jumps to the peak
+// immediately and decays over time, like Finagle's peak-EWMA.
+type peakEWMA struct {
+	mux   sync.Mutex
+	cost  float64
+	stamp time.Time
+}
+
+func (e *peakEWMA) observe(rtt time.Duration) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	now := time.Now()
+	val := float64(rtt)
+	if val > e.cost || e.stamp.IsZero() {
+		e.cost = val
+	} else {
+		w := e.weight(now)
+		e.cost = e.cost*w + val*(1-w)
+	}
+	e.stamp = now
+}
+
+func (e *peakEWMA) reset() {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.cost, e.stamp = 0, time.Time{}
+}
+
+func (e *peakEWMA) value() (time.Duration, bool) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	if e.stamp.IsZero() {
+		return 0, false
+	}
+	return time.Duration(e.cost * e.weight(time.Now())), true
+}
+
+func (e *peakEWMA) weight(now time.Time) float64 {
+	td := now.Sub(e.stamp)
+	if td < 0 {
+		td = 0
+	}
+	return math.Exp(-float64(td) / float64(LatencyDecay))
+}
@@ -1,13 +1,24 @@
 package balancer
 
 import (
+	"context"
+	"errors"
+	"math"
 	"net/http"
 	"strings"
+	"time"
 )
 
 var (
 	// builders is a map from name to balancer builder.
 	builders = make(map[string]Builder)
+
+	// ErrNoBackendAvailable is returned by a Picker when there is no Backend to pick.
+	ErrNoBackendAvailable = errors.New("Picker.Pick(): No Backend available")
+
+	// ErrBodyNotReplayable is returned by Balancer.Do when a retry is needed but the
+	// request body is larger than RetryOptions.MaxBodySize and can not be sent again.
+	ErrBodyNotReplayable = errors.New("Balancer.Do(): request body exceeds the retry buffer and can not be replayed")
 )
 
 // Register registers the balancer builder to the balancer map. b.Name
@@ -30,29 +41,248 @@ func Get(name string) Builder {
 
 // Options contains additional information for Build.
 type Options struct {
-	Name        string           `json:"name" mapstructure:"name"`             //Balancer name
-	Type        string           `json:"type" mapstructure:"type"`             //Picker type
-	Timeout     int              `json:"timeout" mapstructure:"timeout"`       //http timeout, Unit: second
-	CacheSize   int              `json:"cache_size" mapstructure:"cache_size"` //Node cache size
-	NetParam    string           `json:"net_param" mapstructure:"net_param"`   //Node net param
-	Urls        []string         `json:"urls" mapstructure:"urls"`             //Load node url
-	Doctor      DoctorOptions    `json:"doctor" mapstructure:"doctor"`         //Health checker
-	Statistic   StatisticOptions `json:"statistic" mapstructure:"statistic"`   //Statistics
-	DoneHandler DoneHandler      `json:"-"`
-	PingHandler PingHandler      `json:"-"`
+	Name         string           `json:"name" mapstructure:"name"`                   //Balancer name
+	Type         string           `json:"type" mapstructure:"type"`                   //Picker type
+	Timeout      int              `json:"timeout" mapstructure:"timeout"`             //Default timeout of a call without deadline, Unit: second
+	CacheSize    int              `json:"cache_size" mapstructure:"cache_size"`       //Node cache size
+	NetParam     string           `json:"net_param" mapstructure:"net_param"`         //Node net param
+	Urls         []string         `json:"urls" mapstructure:"urls"`                   //Load node url
+	Backends     []BackendOptions `json:"backends" mapstructure:"backends"`           //Load node with settings
+	Doctor       DoctorOptions    `json:"doctor" mapstructure:"doctor"`               //Health checker
+	Statistic    StatisticOptions `json:"statistic" mapstructure:"statistic"`         //Statistics
+	Retry        RetryOptions     `json:"retry" mapstructure:"retry"`                 //Retry policy
+	SlowStart    SlowStartOptions `json:"slow_start" mapstructure:"slow_start"`       //Warm-up of the added and re-admitted nodes
+	DrainTimeout int              `json:"drain_timeout" mapstructure:"drain_timeout"` //Wait for the requests in flight of a removed node, default 30, Unit: second
+	DoneHandler  DoneHandler      `json:"-"`
+	PingHandler  PingHandler      `json:"-"`
+}
+
+// DefaultDrainTimeout is the default drain timeout of a removed node, Unit: second.
+const DefaultDrainTimeout = 30
+
+func (o *Options) drainTimeout() time.Duration {
+	if o.DrainTimeout <= 0 {
+		return DefaultDrainTimeout * time.Second
+	}
+	return time.Duration(o.DrainTimeout) * time.Second
+}
+
+// Nodes returns all the configured nodes, Urls first and then Backends.
+// A node in Backends overrides the settings of the same url in Urls.
+func (o *Options) Nodes() []BackendOptions {
+	nodes := make([]BackendOptions, 0, len(o.Urls)+len(o.Backends))
+	index := make(map[string]int)
+
+	add := func(node BackendOptions) {
+		if len(node.URL) == 0 {
+			return
+		}
+		if i, ok := index[node.URL]; ok {
+			nodes[i] = node
+			return
+		}
+		index[node.URL] = len(nodes)
+		nodes = append(nodes, node)
+	}
+
+	for _, url := range o.Urls {
+		add(BackendOptions{URL: url})
+	}
+	for _, node := range o.Backends {
+		add(node)
+	}
+
+	return nodes
+}
+
+// BackendOptions contains additional information for Backend.
+type BackendOptions struct {
+	URL      string            `json:"url" mapstructure:"url"`           //Node url
+	Weight   int               `json:"weight" mapstructure:"weight"`     //Node weight, default 1
+	Tags     []string          `json:"tags" mapstructure:"tags"`         //Node tags, used by routing hints
+	Headers  map[string]string `json:"headers" mapstructure:"headers"`   //Headers added to every request sent to the node
+	Username string            `json:"username" mapstructure:"username"` //Basic auth username of the node
+	Password string            `json:"password" mapstructure:"password"` //Basic auth password of the node
+}
+
+// SlowStartOptions contains additional information for the warm-up of a node.
+// The share of traffic of a node grows from MinPercent to 100 percent over the window,
+// by the curve (elapsed / window) ^ (1 / Aggression), 1 is linear.
+type SlowStartOptions struct {
+	Enable     bool    `json:"enable" mapstructure:"enable"`           //Enable the slow start
+	Window     int     `json:"window" mapstructure:"window"`           //Warm-up window, default 60, Unit: second
+	Aggression float64 `json:"aggression" mapstructure:"aggression"`   //Curve of the warm-up, default 1, greater is faster at first
+	MinPercent int     `json:"min_percent" mapstructure:"min_percent"` //Minimum share of traffic, default 10
+}
+
+// factor returns the share of traffic of a node which has been warming up for the elapsed time.
+func (o *SlowStartOptions) factor(elapsed time.Duration) float64 {
+	window := time.Duration(o.Window) * time.Second
+	if window <= 0 {
+		window = 60 * time.Second
+	}
+	if elapsed >= window {
+		return 1
+	}
+
+	aggression := o.Aggression
+	if aggression <= 0 {
+		aggression = 1
+	}
+	min := float64(o.MinPercent) / 100
+	if o.MinPercent <= 0 || o.MinPercent > 100 {
+		min = 0.1
+	}
+
+	return math.Max(min, math.Pow(float64(elapsed)/float64(window), 1/aggression))
 }
 
 // DoctorOptions contains additional information for Doctor.
 type DoctorOptions struct {
-	Enable bool   `json:"enable" mapstructure:"enable"` //Whether to enable health check
-	Type   string `json:"type" mapstructure:"type"`     //Doctor type
-	Spec   string `json:"spec" mapstructure:"spec"`     //Time interval of scheduled tasks
+	Enable       bool           `json:"enable" mapstructure:"enable"`               //Whether to enable health check
+	Type         string         `json:"type" mapstructure:"type"`                   //Doctor type
+	Spec         string         `json:"spec" mapstructure:"spec"`                   //Time interval of scheduled tasks
+	Window       int            `json:"window" mapstructure:"window"`               //Window of the failures, default 600, Unit: second
+	MaxFailures  int            `json:"max_failures" mapstructure:"max_failures"`   //Failures in the window to mark a node as dead, default 100
+	FailureRatio float64        `json:"failure_ratio" mapstructure:"failure_ratio"` //Failure ratio in the window to mark a node as dead, 0 to use max_failures
+	MinRequests  int            `json:"min_requests" mapstructure:"min_requests"`   //Minimum requests in the window for the failure ratio, default 100
+	RingSize     int            `json:"ring_size" mapstructure:"ring_size"`         //Number of the last failed errors kept by a node, default 100
+	Healthy      int            `json:"healthy" mapstructure:"healthy"`             //Consecutive successful pings to mark a node as alive, default 10
+	Unhealthy    int            `json:"unhealthy" mapstructure:"unhealthy"`         //Consecutive failed pings to mark a node as dead by the active and chain doctors, default 3
+	Interval     int            `json:"interval" mapstructure:"interval"`           //Interval between the pings of a dead node by the default doctor, default 10, Unit: second
+	Probe        ProbeOptions   `json:"probe" mapstructure:"probe"`                 //How to ping a node
+	Chain        ChainOptions   `json:"chain" mapstructure:"chain"`                 //Chain height check of the ChainHeight doctor
+	Breaker      BreakerOptions `json:"breaker" mapstructure:"breaker"`             //Circuit breaker of each node
+	Outlier      OutlierOptions `json:"outlier" mapstructure:"outlier"`             //Outlier detection of the nodes
+}
+
+// OutlierOptions contains additional information for outlier detection.
+type OutlierOptions struct {
+	Enable             bool    `json:"enable" mapstructure:"enable"`                             //Whether to enable outlier detection
+	Spec               string  `json:"spec" mapstructure:"spec"`                                 //Time interval of detection, default "@every 10s"
+	ConsecutiveErrors  int     `json:"consecutive_errors" mapstructure:"consecutive_errors"`     //Consecutive 5xx or errors to eject a node, default 5
+	SuccessRateStdev   float64 `json:"success_rate_stdev" mapstructure:"success_rate_stdev"`     //Standard deviations below the mean success rate to eject a node, default 1.9
+	MinHosts           int     `json:"min_hosts" mapstructure:"min_hosts"`                       //Minimum nodes with enough requests to check the success rate, default 5
+	MinRequests        int     `json:"min_requests" mapstructure:"min_requests"`                 //Minimum requests of a node in an interval to check its success rate, default 100
+	MaxEjectionPercent int     `json:"max_ejection_percent" mapstructure:"max_ejection_percent"` //Maximum percent of ejected nodes, at least one node can be ejected, default 10
+	BaseEjectionTime   int     `json:"base_ejection_time" mapstructure:"base_ejection_time"`     //Ejection time, doubled each time the node is ejected again, default 30, Unit: second
+	MaxEjectionTime    int     `json:"max_ejection_time" mapstructure:"max_ejection_time"`       //Maximum ejection time, default 300, Unit: second
+}
+
+// Default failure thresholds of DoctorOptions.
+const (
+	DefaultFailureWindow = 600
+	DefaultMaxFailures   = 100
+	DefaultMinRequests   = 100
+	DefaultFailureRing   = 100
+)
+
+// FailurePolicy returns the failure thresholds, the default values are used if they are not set.
+func (o *DoctorOptions) FailurePolicy() FailurePolicy {
+	policy := FailurePolicy{
+		MaxFailures:  o.MaxFailures,
+		FailureRatio: o.FailureRatio,
+		MinRequests:  o.MinRequests,
+	}
+	if policy.MaxFailures <= 0 {
+		policy.MaxFailures = DefaultMaxFailures
+	}
+	if policy.MinRequests <= 0 {
+		policy.MinRequests = DefaultMinRequests
+	}
+	return policy
+}
+
+func (o *DoctorOptions) window() time.Duration {
+	if o.Window <= 0 {
+		return DefaultFailureWindow * time.Second
+	}
+	return time.Duration(o.Window) * time.Second
+}
+
+func (o *DoctorOptions) ringSize() int {
+	if o.RingSize <= 0 {
+		return DefaultFailureRing
+	}
+	return o.RingSize
+}
+
+// HealthyThreshold returns the consecutive successful pings to mark a node as alive.
+func (o *DoctorOptions) HealthyThreshold() int {
+	if o.Healthy <= 0 {
+		return 10
+	}
+	return o.Healthy
+}
+
+// UnhealthyThreshold returns the consecutive failed pings to mark a node as dead.
+func (o *DoctorOptions) UnhealthyThreshold() int {
+	if o.Unhealthy <= 0 {
+		return 3
+	}
+	return o.Unhealthy
+}
+
+// PingInterval returns the interval between the pings of a dead node.
+func (o *DoctorOptions) PingInterval() time.Duration {
+	if o.Interval <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(o.Interval) * time.Second
+}
+
+// ProbeOptions contains additional information for the ping of the doctor.
+// The default probe is the http probe of /net-info for Bytom and Vapor nodes.
+type ProbeOptions struct {
+	Type      string `json:"type" mapstructure:"type"`             //Probe type: http, tcp or chain, default http
+	Method    string `json:"method" mapstructure:"method"`         //Http method, default POST
+	Path      string `json:"path" mapstructure:"path"`             //Http path, default /net-info
+	Status    int    `json:"status" mapstructure:"status"`         //Expected http status, default 200
+	Body      string `json:"body" mapstructure:"body"`             //Regular expression the http body must match
+	JSONPath  string `json:"json_path" mapstructure:"json_path"`   //Dot separated path of a json field, default status for the default path
+	JSONValue string `json:"json_value" mapstructure:"json_value"` //Expected value of the json field, default success
+	Timeout   int    `json:"timeout" mapstructure:"timeout"`       //Timeout of a ping, default 5, Unit: second
+}
+
+// ChainOptions contains additional information for the chain height check.
+type ChainOptions struct {
+	MaxLag       uint64           `json:"max_lag" mapstructure:"max_lag"`             //Blocks a node can be behind the highest block and the best node, default 10
+	NetworkID    string           `json:"network_id" mapstructure:"network_id"`       //Expected network id, default the network id of most nodes
+	AllowSyncing bool             `json:"allow_syncing" mapstructure:"allow_syncing"` //Whether a syncing node is healthy
+	InfoHandler  ChainInfoHandler `json:"-"`
+}
+
+// BreakerOptions contains additional information for CircuitBreaker.
+type BreakerOptions struct {
+	Enable           bool    `json:"enable" mapstructure:"enable"`                         //Whether to enable the circuit breaker
+	FailureRatio     float64 `json:"failure_ratio" mapstructure:"failure_ratio"`           //Failure ratio to open the breaker, default 0.5
+	MinRequests      int     `json:"min_requests" mapstructure:"min_requests"`             //Minimum requests in the window to open the breaker, default 20
+	Window           int     `json:"window" mapstructure:"window"`                         //Window of the failure ratio, default 10, Unit: second
+	OpenDuration     int     `json:"open_duration" mapstructure:"open_duration"`           //Time before the open breaker becomes half-open, default 30, Unit: second
+	HalfOpenRequests int     `json:"half_open_requests" mapstructure:"half_open_requests"` //Successful trial requests to close the breaker, default 5
+}
+
+// RetryOptions contains additional information for retry.
+// Each retry is sent to a backend that has not been tried by the request.
+type RetryOptions struct {
+	MaxAttempts int      `json:"max_attempts" mapstructure:"max_attempts"`   //Maximum attempts including the first one, default 4, 1 disables retry
+	Statuses    []int    `json:"statuses" mapstructure:"statuses"`           //Retryable status codes, default 429, 500, 502, 503, 504
+	Errors      []string `json:"errors" mapstructure:"errors"`               //Retryable errors containing any of the messages, default all errors
+	Backoff     int      `json:"backoff" mapstructure:"backoff"`             //Base of the exponential backoff, default 50, Unit: millisecond
+	MaxBackoff  int      `json:"max_backoff" mapstructure:"max_backoff"`     //Maximum backoff, default 1000, Unit: millisecond
+	Jitter      float64  `json:"jitter" mapstructure:"jitter"`               //Random part of the backoff, between 0 and 1, default 0.2
+	Methods     []string `json:"methods" mapstructure:"methods"`             //Idempotent methods, default GET, HEAD, OPTIONS, PUT, DELETE, TRACE
+	Paths       []string `json:"paths" mapstructure:"paths"`                 //Idempotent paths of any method
+	Budget      float64  `json:"budget" mapstructure:"budget"`               //Maximum ratio of retries to requests, default 0.2
+	MinRetries  int      `json:"min_retries" mapstructure:"min_retries"`     //Retries per second allowed regardless of the budget, default 10
+	MaxBodySize int      `json:"max_body_size" mapstructure:"max_body_size"` //Maximum request body buffered for retry, default 1MB, Unit: byte
 }
 
 // StatisticOptions contains additional information for Statistic.
 type StatisticOptions struct {
-	Enable bool `json:"enable" mapstructure:"enable"` //Whether to enable statistics
-	Port   int  `json:"port" mapstructure:"port"`     //Service port for obtaining statistics
+	Enable bool   `json:"enable" mapstructure:"enable"` //Whether to enable statistics
+	Port   int    `json:"port" mapstructure:"port"`     //Service port for obtaining statistics
+	Token  string `json:"token" mapstructure:"token"`   //Bearer token of the admin api, the same for the balancers of the port, the admin api is disabled if it is empty
 }
 
 // Builder creates a balancer.
@@ -65,7 +295,9 @@ type Builder interface {
 // the connectivity states.
 type Balancer interface {
 	Do(req *http.Request) (*http.Response, error)
+	DoContext(ctx context.Context, req *http.Request) (*http.Response, error)
 	Pick() (*Backend, error)
+	PickByKey(key string, n int) ([]*Backend, error)
 	Backends() *Backends
 	Close()
 }
@@ -80,9 +312,44 @@ type Picker interface {
 	Pick() (*Backend, error)
 }
 
+// PickInfo contains additional information for pick.
+type PickInfo struct {
+	Request *http.Request   //The request to send, nil if picking without request
+	Ctx     context.Context //The context of the request
+	Attempt int             //Attempt number, starting from 0
+	Tried   []*Backend      //Backends already tried by the previous attempts
+	Hints   *RoutingHints   //Routing hints of the request
+}
+
+// Allow reports whether the backend has not been tried and satisfies the routing hints.
+func (i *PickInfo) Allow(backend *Backend) bool {
+	if i == nil {
+		return true
+	}
+	for _, tried := range i.Tried {
+		if tried == backend {
+			return false
+		}
+	}
+	return i.Hints.Allow(backend)
+}
+
+// InfoPicker is a Picker that uses the request information to pick a Backend.
+// The Picker which only implements Pick is adapted by the base balancer.
+type InfoPicker interface {
+	Picker
+	PickWithInfo(info *PickInfo) (*Backend, error)
+}
+
+// KeyPicker is a Picker that maps a key to n distinct backends, e.g. for sticky routing.
+type KeyPicker interface {
+	Picker
+	PickByKey(key string, n int) ([]*Backend, error)
+}
+
 // DoctorBuilder creates balancer.Doctor.
 type DoctorBuilder interface {
-	Build(ping PingHandler, backends *Backends) Doctor
+	Build(ping PingHandler, backends *Backends, opts *DoctorOptions) Doctor
 	Name() string
 }
 
@@ -92,6 +359,13 @@ type Doctor interface {
 	Ping(backend *Backend) error
 }
 
+// BackendDoctor is a Doctor which checks the backends one by one,
+// each backend is checked by its own job and the job is removed with the backend.
+type BackendDoctor interface {
+	Doctor
+	Check(backend *Backend)
+}
+
 // DoneInfo contains additional information for done.
 type DoneInfo struct {
 	Backend  *Backend
@@ -102,5 +376,16 @@ type DoneInfo struct {
 // DoneHandler define the specific implementation of Done
 type DoneHandler func(info DoneInfo)
 
+// ChainInfo contains the chain state of a node.
+type ChainInfo struct {
+	Syncing      bool   `json:"syncing"`
+	CurrentBlock uint64 `json:"current_block"`
+	HighestBlock uint64 `json:"highest_block"`
+	NetWorkID    string `json:"network_id"`
+}
+
+// ChainInfoHandler define how to get the chain state of a node
+type ChainInfoHandler func(backend *Backend) (*ChainInfo, error)
+
 // PingHandler define the specific implementation of Ping
 type PingHandler func(backend *Backend) error
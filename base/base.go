@@ -1,9 +1,14 @@
 package base
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/bytom/blockcenter/balancer"
 	"github.com/bytom/blockcenter/balancer/health"
@@ -25,49 +30,93 @@ func NewBalancerBuilder(name string, pb balancer.PickerBuilder) balancer.Builder
 
 func (bb *baseBuilder) Build(client *http.Client, opts *balancer.Options) balancer.Balancer {
 	backends := balancer.NewBackends()
-	for _, url := range opts.Urls {
-		if len(url) == 0 {
-			continue
-		}
-		backends.Add(balancer.NewBackend(url, opts.CacheSize))
+	for _, node := range opts.Nodes() {
+		backends.Add(balancer.NewBackendWithOptions(node, opts))
 	}
 
+	scheduler := task.NewScheduler()
+	unwatch := func() {}
 	var doctor balancer.Doctor
 	if opts.Doctor.Enable {
 		doctorBuilder := health.Get(opts.Doctor.Type)
 		if doctorBuilder != nil {
 			if opts.DoneHandler == nil {
-				opts.DoneHandler = health.Done
+				opts.DoneHandler = health.NewDoneHandler(&opts.Doctor)
 			}
 			if opts.PingHandler == nil {
-				opts.PingHandler = health.BytomPing
+				ping, err := health.NewPingHandler(&opts.Doctor)
+				if err != nil {
+					panic(err)
+				}
+				opts.PingHandler = ping
 			}
 
-			doctor = doctorBuilder.Build(opts.PingHandler, backends)
+			doctor = doctorBuilder.Build(opts.PingHandler, backends, &opts.Doctor)
 
 			if len(opts.Doctor.Spec) == 0 {
 				opts.Doctor.Spec = "0 */1 * * * ?"
 			}
-			err := task.Start(task.NewJob(opts.Doctor.Spec, func() {
-				doctor.HealthCheck()
-			}))
-			if err != nil {
+			var err error
+			if unwatch, err = scheduleDoctor(scheduler, doctor, backends, opts.Doctor.Spec); err != nil {
 				panic(err)
 			}
 		}
 	}
 
+	if opts.Doctor.Outlier.Enable {
+		detector := health.NewOutlierDetector(&opts.Doctor.Outlier, backends)
+		if err := scheduler.Add("outlier", task.NewJob(opts.Doctor.Outlier.Spec, func() {
+			detector.Detect()
+		})); err != nil {
+			panic(err)
+		}
+	}
+
+	if scheduler.Len() > 0 {
+		scheduler.Start()
+	}
+
+	picker := bb.pickerBuilder.Build(backends)
+	infoPicker, ok := picker.(balancer.InfoPicker)
+	if !ok {
+		infoPicker = &pickerAdapter{
+			Picker:   picker,
+			backends: backends,
+		}
+	}
+	if opts.SlowStart.Enable {
+		infoPicker = &warmupPicker{
+			InfoPicker: infoPicker,
+			backends:   backends,
+		}
+	}
+	keyPicker, ok := picker.(balancer.KeyPicker)
+	if !ok {
+		keyPicker = &hashKeyPicker{
+			Picker:         picker,
+			ConsistentHash: balancer.NewConsistentHash(backends, balancer.DefaultReplicas),
+		}
+	}
+
 	loadBalancing := &baseBalancer{
+		timeout:   time.Duration(opts.Timeout) * time.Second,
+		retry:     newRetryPolicy(&opts.Retry),
 		statistic: &opts.Statistic,
 		client:    client,
-		picker:    bb.pickerBuilder.Build(backends),
+		picker:    infoPicker,
+		keyPicker: keyPicker,
 		doctor:    doctor,
+		scheduler: scheduler,
+		unwatch:   unwatch,
 		done:      opts.DoneHandler,
 		backends:  backends,
 	}
 
 	if opts.Statistic.Enable {
-		go statistic.ServerAndRun(&opts.Statistic)
+		// the server is shared by the balancers of the same port
+		if _, err := statistic.StartWithOptions(&opts.Statistic); err != nil {
+			fmt.Println(err)
+		}
 	}
 
 	return loadBalancing
@@ -77,17 +126,87 @@ func (bb *baseBuilder) Name() string {
 	return bb.name
 }
 
+// scheduleDoctor schedules a job for each backend if the doctor is a BackendDoctor,
+// the jobs are added and removed with the backends until unwatch is called, otherwise one job checks all the backends.
+func scheduleDoctor(scheduler *task.Scheduler, doctor balancer.Doctor, backends *balancer.Backends, spec string) (unwatch func(), err error) {
+	bd, ok := doctor.(balancer.BackendDoctor)
+	if !ok {
+		return func() {}, scheduler.Add("doctor", task.NewJob(spec, func() {
+			doctor.HealthCheck()
+		}))
+	}
+
+	add := func(backend *balancer.Backend) error {
+		return scheduler.Add("doctor:"+backend.URL, task.NewJob(spec, func() {
+			bd.Check(backend)
+		}))
+	}
+
+	backends.Range(func(index int, backend *balancer.Backend) bool {
+		err = add(backend)
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	unwatch = backends.Watch(func(backend *balancer.Backend, added bool) {
+		if added {
+			_ = add(backend)
+		} else {
+			scheduler.Remove("doctor:" + backend.URL)
+		}
+	})
+	return unwatch, nil
+}
+
 type baseBalancer struct {
 	client    *http.Client
+	timeout   time.Duration
 	backends  *balancer.Backends
 	statistic *balancer.StatisticOptions
-	picker    balancer.Picker
+	picker    balancer.InfoPicker
+	keyPicker balancer.KeyPicker
 	doctor    balancer.Doctor
+	scheduler *task.Scheduler
+	unwatch   func()
 	done      balancer.DoneHandler
+	retry     *retryPolicy
 }
 
 func (b *baseBalancer) Pick() (*balancer.Backend, error) {
-	backend, err := b.picker.Pick()
+	return b.pick(&balancer.PickInfo{})
+}
+
+// pick picks a backend for the preferred backend and the hash key of the routing hints,
+// otherwise by the picker.
+func (b *baseBalancer) pick(info *balancer.PickInfo) (*balancer.Backend, error) {
+	if info.Ctx != nil && info.Ctx.Err() != nil {
+		return nil, info.Ctx.Err()
+	}
+
+	if hints := info.Hints; hints != nil {
+		if len(hints.Preferred) > 0 {
+			if backend, ok := b.backends.Get(hints.Preferred); ok && backend.Available() && info.Allow(backend) {
+				return backend, nil
+			}
+		}
+
+		if len(hints.HashKey) > 0 {
+			backends, err := b.keyPicker.PickByKey(hints.HashKey, b.backends.Len())
+			if err != nil {
+				return nil, err
+			}
+			for _, backend := range backends {
+				if info.Allow(backend) {
+					return backend, nil
+				}
+			}
+			return nil, balancer.ErrNoBackendAvailable
+		}
+	}
+
+	backend, err := b.picker.PickWithInfo(info)
 	if err != nil {
 		return nil, err
 	}
@@ -97,7 +216,30 @@ func (b *baseBalancer) Pick() (*balancer.Backend, error) {
 	return backend, nil
 }
 
-func (b *baseBalancer) Do(req *http.Request) (resp *http.Response, err error) {
+// acquire picks a backend and takes a trial request of its circuit breaker,
+// the backends whose breaker rejects the request are skipped.
+func (b *baseBalancer) acquire(info *balancer.PickInfo) (*balancer.Backend, error) {
+	for {
+		backend, err := b.pick(info)
+		if err != nil {
+			return nil, err
+		}
+		if backend.Breaker == nil || backend.Breaker.Allow() {
+			return backend, nil
+		}
+		info.Tried = append(info.Tried, backend)
+	}
+}
+
+func (b *baseBalancer) PickByKey(key string, n int) ([]*balancer.Backend, error) {
+	return b.keyPicker.PickByKey(key, n)
+}
+
+func (b *baseBalancer) Do(req *http.Request) (*http.Response, error) {
+	return b.DoContext(req.Context(), req)
+}
+
+func (b *baseBalancer) DoContext(ctx context.Context, req *http.Request) (resp *http.Response, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			var str string
@@ -113,38 +255,129 @@ func (b *baseBalancer) Do(req *http.Request) (resp *http.Response, err error) {
 		}
 	}()
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// the default timeout applies to the whole call when the context has no deadline,
+	// and it is released when the response body is closed
+	if _, ok := ctx.Deadline(); !ok && b.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.timeout)
+		defer func() {
+			if err != nil || resp == nil {
+				cancel()
+			} else {
+				resp.Body = newBodyCloser(resp.Body, cancel)
+			}
+		}()
+	}
+
+	return b.do(req.WithContext(ctx))
+}
+
+func (b *baseBalancer) do(req *http.Request) (resp *http.Response, err error) {
 	url := req.URL.String()
 	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
 		return b.client.Do(req)
 	}
 
-	backend, err := b.picker.Pick()
+	b.retry.request()
+	body, err := newReplayableBody(req, b.retry.opts.MaxBodySize)
 	if err != nil {
 		return nil, err
 	}
-	if backend == nil {
-		return nil, errors.New("Picker.Pick(): nil backend")
+	defer body.Close()
+
+	info := &balancer.PickInfo{
+		Request: req,
+		Ctx:     req.Context(),
+		Hints:   balancer.HintsFromContext(req.Context()),
 	}
 
-	var newurl string
-	if strings.HasPrefix(backend.URL, "http://") || strings.HasPrefix(backend.URL, "https://") {
-		newurl = balancer.URLJoin(backend.URL, url)
-	} else {
-		newurl = balancer.URLJoin("http://", backend.URL, url)
+	for {
+		backend, perr := b.acquire(info)
+		if perr != nil {
+			if info.Attempt == 0 {
+				return nil, perr
+			}
+			// no other backend to retry, return the result of the last attempt
+			return resp, err
+		}
+
+		if resp != nil {
+			discard(resp)
+		}
+
+		reqBody, berr := body.next()
+		if berr != nil {
+			release(backend)
+			return nil, berr
+		}
+		if info.Attempt > 0 {
+			backend.Statistic.IncRetries()
+		}
+		resp, err = b.send(req, backend, reqBody)
+		info.Tried = append(info.Tried, backend)
+
+		if !b.retry.shouldRetry(req, info.Attempt+1, resp, err) {
+			return resp, err
+		}
+		if !body.replayable() {
+			if resp != nil {
+				discard(resp)
+			}
+			return nil, fmt.Errorf("%w: %d bytes", balancer.ErrBodyNotReplayable, b.retry.opts.MaxBodySize)
+		}
+		if !b.retry.withdraw() || !sleep(req.Context(), b.retry.backoff(info.Attempt+1)) {
+			return resp, err
+		}
+		info.Attempt++
 	}
+}
 
-	if req, err = http.NewRequest(req.Method, newurl, req.Body); err != nil {
-		return nil, err
+// send sends the request to the backend with the body of this attempt.
+func (b *baseBalancer) send(req *http.Request, backend *balancer.Backend, body io.Reader) (resp *http.Response, err error) {
+	if body != nil {
+		body = &countingBody{Reader: body, add: backend.Statistic.AddBytesSent}
 	}
+	req = rewrite(req, backend, body)
 
+	backend.IncInFlight()
+	start := time.Now()
 	resp, err = b.client.Do(req)
+	if err != nil {
+		backend.DecInFlight()
+	} else {
+		backend.Statistic.ObserveLatency(time.Since(start))
+		// the request is finished when the response body is closed
+		resp.Body = newBodyCloser(&countingBody{Reader: resp.Body, add: backend.Statistic.AddBytesReceived}, func() {
+			backend.DecInFlight()
+		})
+	}
 
-	if b.statistic.Enable {
-		if err == nil && (resp != nil && resp.StatusCode < 400) {
-			backend.Statistic.IncSuccess()
-		} else {
-			backend.Statistic.IncFailure()
-		}
+	if errors.Is(err, context.Canceled) {
+		// the canceled request says nothing about the backend
+		release(backend)
+	} else if backend.Breaker != nil {
+		backend.Breaker.Done(err == nil && resp.StatusCode < 500)
+	}
+
+	// the statistics are always counted, they are used by the pickers and the outlier detector
+	if err == nil && (resp != nil && resp.StatusCode < 400) {
+		backend.Statistic.IncSuccess()
+	} else {
+		backend.Statistic.IncFailure()
+	}
+	if err != nil {
+		backend.Statistic.IncStatus(0)
+	} else {
+		backend.Statistic.IncStatus(resp.StatusCode)
+	}
+	if err != nil || resp.StatusCode >= 500 {
+		backend.Statistic.IncConsecutiveErrors()
+	} else {
+		backend.Statistic.ResetConsecutiveErrors()
 	}
 
 	if b.done != nil {
@@ -158,10 +391,56 @@ func (b *baseBalancer) Do(req *http.Request) (resp *http.Response, err error) {
 	return resp, err
 }
 
-func (b *baseBalancer) Close() {
-	if b.doctor != nil {
-		task.Stop()
+// release gives back the trial request of the circuit breaker taken by acquire.
+func release(backend *balancer.Backend) {
+	if backend.Breaker != nil {
+		backend.Breaker.Release()
+	}
+}
+
+// rewrite clones the request with its headers and context, and rewrites the scheme,
+// host and path prefix to the backend, the headers of the backend are added.
+func rewrite(req *http.Request, backend *balancer.Backend, body io.Reader) *http.Request {
+	target := backend.Target()
+	outreq := req.Clone(req.Context())
+	outreq.RequestURI = ""
+
+	outreq.URL.Scheme = target.Scheme
+	outreq.URL.Host = target.Host
+	outreq.URL.Path = balancer.URLJoin(target.Path, req.URL.Path)
+	outreq.URL.RawPath = ""
+	if len(target.RawQuery) > 0 {
+		if len(outreq.URL.RawQuery) > 0 {
+			outreq.URL.RawQuery = target.RawQuery + "&" + outreq.URL.RawQuery
+		} else {
+			outreq.URL.RawQuery = target.RawQuery
+		}
 	}
+
+	switch rc := body.(type) {
+	case nil:
+		outreq.Body = nil
+	case io.ReadCloser:
+		outreq.Body = rc
+	default:
+		outreq.Body = ioutil.NopCloser(body)
+	}
+
+	if target.User != nil {
+		password, _ := target.User.Password()
+		outreq.SetBasicAuth(target.User.Username(), password)
+	}
+	for k, v := range backend.Header() {
+		outreq.Header[k] = v
+	}
+
+	return outreq
+}
+
+func (b *baseBalancer) Close() {
+	// the added backends are not scheduled on the stopped scheduler
+	b.unwatch()
+	b.scheduler.Stop()
 }
 
 func (b *baseBalancer) Backends() *balancer.Backends {
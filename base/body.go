@@ -0,0 +1,117 @@
+package base
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/bytom/blockcenter/balancer"
+)
+
+// bodyCloser calls onClose once the response body is closed.
+type bodyCloser struct {
+	io.ReadCloser
+	once    sync.Once
+	onClose func()
+}
+
+func newBodyCloser(body io.ReadCloser, onClose func()) io.ReadCloser {
+	return &bodyCloser{
+		ReadCloser: body,
+		onClose:    onClose,
+	}
+}
+
+func (b *bodyCloser) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.onClose)
+	return err
+}
+
+// countingBody counts the bytes read from the body.
+type countingBody struct {
+	io.Reader
+	add func(n int) uint64
+}
+
+func (c *countingBody) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	if n > 0 {
+		c.add(n)
+	}
+	return n, err
+}
+
+func (c *countingBody) Close() error {
+	if closer, ok := c.Reader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// replayableBody returns the request body for every attempt. The body is rewound
+// by Request.GetBody if it is set, otherwise it is buffered up to the limit, a body
+// larger than the limit is streamed to the first attempt and can not be replayed.
+type replayableBody struct {
+	req      *http.Request
+	buf      []byte
+	rest     io.ReadCloser
+	attempts int
+}
+
+func newReplayableBody(req *http.Request, limit int) (*replayableBody, error) {
+	b := &replayableBody{req: req}
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return b, nil
+	}
+
+	buf, err := ioutil.ReadAll(io.LimitReader(req.Body, int64(limit)+1))
+	if err != nil {
+		_ = req.Body.Close()
+		return nil, err
+	}
+
+	b.buf = buf
+	if len(buf) > limit {
+		b.rest = req.Body
+	} else {
+		_ = req.Body.Close()
+	}
+	return b, nil
+}
+
+// replayable reports whether the body can be sent again.
+func (b *replayableBody) replayable() bool {
+	return b.rest == nil
+}
+
+// next returns the body of the next attempt.
+func (b *replayableBody) next() (io.Reader, error) {
+	b.attempts++
+	switch {
+	case b.req.Body == nil || b.req.Body == http.NoBody:
+		return nil, nil
+	case b.req.GetBody != nil:
+		if b.attempts == 1 {
+			return b.req.Body, nil
+		}
+		return b.req.GetBody()
+	case b.rest != nil:
+		if b.attempts > 1 {
+			return nil, balancer.ErrBodyNotReplayable
+		}
+		return io.MultiReader(bytes.NewReader(b.buf), b.rest), nil
+	default:
+		return bytes.NewReader(b.buf), nil
+	}
+}
+
+// Close closes the part of the body which is not read.
+func (b *replayableBody) Close() error {
+	if b.rest != nil {
+		return b.rest.Close()
+	}
+	return nil
+}
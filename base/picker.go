@@ -0,0 +1,69 @@
+package base
+
+import (
+	"math/rand"
+
+	"github.com/bytom/blockcenter/balancer"
+)
+
+// pickerAdapter adapts the picker which only implements Pick to balancer.InfoPicker,
+// it picks again until the backend is allowed by the pick information.
+type pickerAdapter struct {
+	balancer.Picker
+	backends *balancer.Backends
+}
+
+func (p *pickerAdapter) PickWithInfo(info *balancer.PickInfo) (*balancer.Backend, error) {
+	length := p.backends.Len()
+	for i := 0; i < length; i++ {
+		backend, err := p.Pick()
+		if err != nil {
+			return nil, err
+		}
+		if info.Allow(backend) {
+			return backend, nil
+		}
+	}
+	return nil, balancer.ErrNoBackendAvailable
+}
+
+// warmupPicker accepts a warming up backend picked by the picker with the chance of its
+// warm-up factor, otherwise it picks another one, so the slow start works with all the pickers.
+// The warmest rejected backend is returned if there is no other backend.
+type warmupPicker struct {
+	balancer.InfoPicker
+	backends *balancer.Backends
+}
+
+func (p *warmupPicker) PickWithInfo(info *balancer.PickInfo) (*balancer.Backend, error) {
+	var fallback *balancer.Backend
+	var best float64
+	trial := *info
+	length := p.backends.Len()
+	for i := 0; i <= length; i++ {
+		backend, err := p.InfoPicker.PickWithInfo(&trial)
+		if err != nil {
+			if fallback != nil {
+				return fallback, nil
+			}
+			return nil, err
+		}
+
+		factor := backend.WarmupFactor()
+		if factor >= 1 || rand.Float64() < factor {
+			return backend, nil
+		}
+		if fallback == nil || factor > best {
+			fallback, best = backend, factor
+		}
+		// the tried backends of the pick information are not changed
+		trial.Tried = append(trial.Tried[:len(trial.Tried):len(trial.Tried)], backend)
+	}
+	return fallback, nil
+}
+
+// hashKeyPicker adds the consistent hash to the picker which can not pick by key.
+type hashKeyPicker struct {
+	balancer.Picker
+	*balancer.ConsistentHash
+}
@@ -0,0 +1,214 @@
+package base
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytom/blockcenter/balancer"
+)
+
+var (
+	defaultRetryStatuses = []int{http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+	defaultRetryMethods  = []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace}
+)
+
+// retryPolicy decides whether a request is retried and how long to wait before the retry.
+type retryPolicy struct {
+	opts     *balancer.RetryOptions
+	statuses map[int]struct{}
+	methods  map[string]struct{}
+	paths    map[string]struct{}
+	budget   *retryBudget
+}
+
+func newRetryPolicy(opts *balancer.RetryOptions) *retryPolicy {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 4
+	}
+	if len(opts.Statuses) == 0 {
+		opts.Statuses = defaultRetryStatuses
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 50
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 1000
+	}
+	if opts.Jitter <= 0 || opts.Jitter > 1 {
+		opts.Jitter = 0.2
+	}
+	if len(opts.Methods) == 0 {
+		opts.Methods = defaultRetryMethods
+	}
+	if opts.Budget <= 0 {
+		opts.Budget = 0.2
+	}
+	if opts.MinRetries <= 0 {
+		opts.MinRetries = 10
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = 1 << 20
+	}
+
+	p := &retryPolicy{
+		opts:     opts,
+		statuses: make(map[int]struct{}),
+		methods:  make(map[string]struct{}),
+		paths:    make(map[string]struct{}),
+		budget:   newRetryBudget(opts.Budget, opts.MinRetries),
+	}
+	for _, status := range opts.Statuses {
+		p.statuses[status] = struct{}{}
+	}
+	for _, method := range opts.Methods {
+		p.methods[strings.ToUpper(method)] = struct{}{}
+	}
+	for _, path := range opts.Paths {
+		p.paths[path] = struct{}{}
+	}
+	return p
+}
+
+// request records a request for the retry budget.
+func (p *retryPolicy) request() {
+	p.budget.deposit()
+}
+
+// shouldRetry reports whether the request is retried after the attempts.
+func (p *retryPolicy) shouldRetry(req *http.Request, attempts int, resp *http.Response, err error) bool {
+	if attempts >= p.opts.MaxAttempts || req.Context().Err() != nil {
+		return false
+	}
+	if !p.idempotent(req) {
+		return false
+	}
+	return p.retryable(resp, err)
+}
+
+// withdraw takes a retry from the retry budget, it returns false if the budget is exhausted.
+func (p *retryPolicy) withdraw() bool {
+	return p.budget.withdraw()
+}
+
+func (p *retryPolicy) idempotent(req *http.Request) bool {
+	if _, ok := p.methods[req.Method]; ok {
+		return true
+	}
+	_, ok := p.paths[req.URL.Path]
+	return ok
+}
+
+func (p *retryPolicy) retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		if len(p.opts.Errors) == 0 {
+			return true
+		}
+		for _, msg := range p.opts.Errors {
+			if strings.Contains(err.Error(), msg) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if resp == nil {
+		return false
+	}
+	_, ok := p.statuses[resp.StatusCode]
+	return ok
+}
+
+// backoff returns the exponential backoff with jitter before the retry after the attempts.
+func (p *retryPolicy) backoff(attempts int) time.Duration {
+	backoff := time.Duration(p.opts.Backoff) * time.Millisecond
+	max := time.Duration(p.opts.MaxBackoff) * time.Millisecond
+	for i := 1; i < attempts && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
+	}
+
+	jitter := time.Duration(float64(backoff) * p.opts.Jitter * rand.Float64())
+	return backoff - time.Duration(float64(backoff)*p.opts.Jitter) + jitter
+}
+
+// sleep waits for the duration, it returns false if the context is done.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// discard drains and closes the response body so that the connection can be reused.
+func discard(resp *http.Response) {
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+	_ = resp.Body.Close()
+}
+
+// retryBudget limits the retries to a ratio of the requests in the last 10 seconds.
+type retryBudget struct {
+	ratio      float64
+	minRetries int
+	buckets    [10]budgetBucket
+	mux        sync.Mutex
+}
+
+type budgetBucket struct {
+	second   int64
+	requests int
+	retries  int
+}
+
+func newRetryBudget(ratio float64, minRetries int) *retryBudget {
+	return &retryBudget{
+		ratio:      ratio,
+		minRetries: minRetries,
+	}
+}
+
+func (b *retryBudget) deposit() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.bucket(time.Now().Unix()).requests++
+}
+
+func (b *retryBudget) withdraw() bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	now := time.Now().Unix()
+	var requests, retries int
+	for _, bucket := range b.buckets {
+		if now-bucket.second < int64(len(b.buckets)) {
+			requests += bucket.requests
+			retries += bucket.retries
+		}
+	}
+
+	if float64(retries) >= b.ratio*float64(requests)+float64(b.minRetries*len(b.buckets)) {
+		return false
+	}
+	b.bucket(now).retries++
+	return true
+}
+
+func (b *retryBudget) bucket(second int64) *budgetBucket {
+	bucket := &b.buckets[second%int64(len(b.buckets))]
+	if bucket.second != second {
+		*bucket = budgetBucket{second: second}
+	}
+	return bucket
+}
@@ -0,0 +1,175 @@
+package balancer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of CircuitBreaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets all requests through and counts the failures.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects all requests until the open duration elapses.
+	BreakerOpen
+	// BreakerHalfOpen lets a limited number of trial requests through.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker stops sending requests to a failing node, and probes it with
+// trial requests after a while.
+type CircuitBreaker struct {
+	opts      BreakerOptions
+	state     BreakerState
+	window    *SlidingWindow
+	openUntil time.Time
+	trials    int
+	successes int
+	mux       sync.Mutex
+	notify    func(typ EventType, reason string)
+}
+
+// NewCircuitBreaker creates a closed CircuitBreaker.
+func NewCircuitBreaker(opts BreakerOptions) *CircuitBreaker {
+	if opts.FailureRatio <= 0 || opts.FailureRatio > 1 {
+		opts.FailureRatio = 0.5
+	}
+	if opts.MinRequests <= 0 {
+		opts.MinRequests = 20
+	}
+	if opts.Window <= 0 {
+		opts.Window = 10
+	}
+	if opts.OpenDuration <= 0 {
+		opts.OpenDuration = 30
+	}
+	if opts.HalfOpenRequests <= 0 {
+		opts.HalfOpenRequests = 5
+	}
+
+	return &CircuitBreaker{
+		opts:   opts,
+		state:  BreakerClosed,
+		window: NewSlidingWindow(time.Duration(opts.Window)*time.Second, opts.Window),
+	}
+}
+
+// State return the current state of the breaker
+func (c *CircuitBreaker) State() BreakerState {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.current(time.Now())
+}
+
+// Ready reports whether a request can be sent, it does not take a trial request.
+func (c *CircuitBreaker) Ready() bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	switch c.current(time.Now()) {
+	case BreakerOpen:
+		return false
+	case BreakerHalfOpen:
+		return c.trials < c.opts.HalfOpenRequests
+	default:
+		return true
+	}
+}
+
+// Allow reports whether a request can be sent, it takes a trial request when half-open.
+// The result of an allowed request must be reported by Done, or Release if there is no result.
+func (c *CircuitBreaker) Allow() bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	switch c.current(time.Now()) {
+	case BreakerOpen:
+		return false
+	case BreakerHalfOpen:
+		if c.trials >= c.opts.HalfOpenRequests {
+			return false
+		}
+		c.trials++
+		return true
+	default:
+		return true
+	}
+}
+
+// Release gives back the trial request taken by Allow when the request ends without a result,
+// e.g. it is canceled, otherwise the half-open breaker would run out of trial requests.
+func (c *CircuitBreaker) Release() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if c.current(time.Now()) == BreakerHalfOpen && c.trials > 0 {
+		c.trials--
+	}
+}
+
+// Done records the result of a request.
+func (c *CircuitBreaker) Done(success bool) {
+	c.mux.Lock()
+
+	var event EventType
+	var reason string
+	now := time.Now()
+	switch c.current(now) {
+	case BreakerClosed:
+		c.window.Add(success)
+		successes, failures := c.window.Counts()
+		total := successes + failures
+		if total >= uint64(c.opts.MinRequests) && float64(failures) >= c.opts.FailureRatio*float64(total) {
+			c.open(now)
+			event, reason = EventCircuitOpened, fmt.Sprintf("%d failures of %d requests", failures, total)
+		}
+	case BreakerHalfOpen:
+		if !success {
+			c.open(now)
+			event, reason = EventCircuitOpened, "trial request failed"
+			break
+		}
+		c.successes++
+		if c.successes >= c.opts.HalfOpenRequests {
+			c.state = BreakerClosed
+			c.window.Reset()
+			event, reason = EventCircuitClosed, fmt.Sprintf("%d trial requests succeeded", c.successes)
+		}
+	}
+	c.mux.Unlock()
+
+	// the event is sent without the lock
+	if len(reason) > 0 && c.notify != nil {
+		c.notify(event, reason)
+	}
+}
+
+// current moves the open breaker to half-open when the open duration elapses.
+func (c *CircuitBreaker) current(now time.Time) BreakerState {
+	if c.state == BreakerOpen && !now.Before(c.openUntil) {
+		c.state = BreakerHalfOpen
+		c.trials = 0
+		c.successes = 0
+	}
+	return c.state
+}
+
+func (c *CircuitBreaker) open(now time.Time) {
+	c.state = BreakerOpen
+	c.openUntil = now.Add(time.Duration(c.opts.OpenDuration) * time.Second)
+}
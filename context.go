@@ -0,0 +1,82 @@
+package balancer
+
+import (
+	"context"
+)
+
+type hintsKey struct{}
+
+// RoutingHints contains the routing information attached to the context of a request.
+type RoutingHints struct {
+	HashKey   string   //Requests with the same key are sent to the same backend
+	Preferred string   //Url of the backend to use if it is available
+	Excluded  []string //Url of the backends not to use
+	Tags      []string //Tags the backend must have
+}
+
+// Allow reports whether the backend satisfies the excluded backends and the required tags.
+func (h *RoutingHints) Allow(backend *Backend) bool {
+	if h == nil {
+		return true
+	}
+	for _, url := range h.Excluded {
+		if backend.URL == url {
+			return false
+		}
+	}
+	return backend.HasTags(h.Tags...)
+}
+
+func (h *RoutingHints) clone() *RoutingHints {
+	if h == nil {
+		return &RoutingHints{}
+	}
+	return &RoutingHints{
+		HashKey:   h.HashKey,
+		Preferred: h.Preferred,
+		Excluded:  append([]string(nil), h.Excluded...),
+		Tags:      append([]string(nil), h.Tags...),
+	}
+}
+
+// HintsFromContext returns the routing hints of the context, or nil if there is none.
+func HintsFromContext(ctx context.Context) *RoutingHints {
+	if ctx == nil {
+		return nil
+	}
+	hints, _ := ctx.Value(hintsKey{}).(*RoutingHints)
+	return hints
+}
+
+// WithHints returns a copy of ctx with the routing hints.
+func WithHints(ctx context.Context, hints *RoutingHints) context.Context {
+	return context.WithValue(ctx, hintsKey{}, hints)
+}
+
+// WithHashKey returns a copy of ctx with the hash key, requests with the same key are sent to the same backend.
+func WithHashKey(ctx context.Context, key string) context.Context {
+	hints := HintsFromContext(ctx).clone()
+	hints.HashKey = key
+	return WithHints(ctx, hints)
+}
+
+// WithPreferredBackend returns a copy of ctx with the url of the preferred backend.
+func WithPreferredBackend(ctx context.Context, url string) context.Context {
+	hints := HintsFromContext(ctx).clone()
+	hints.Preferred = url
+	return WithHints(ctx, hints)
+}
+
+// WithExcludedBackends returns a copy of ctx which excludes the backends of the urls.
+func WithExcludedBackends(ctx context.Context, urls ...string) context.Context {
+	hints := HintsFromContext(ctx).clone()
+	hints.Excluded = append(hints.Excluded, urls...)
+	return WithHints(ctx, hints)
+}
+
+// WithRequiredTags returns a copy of ctx which requires the backend to have all the tags.
+func WithRequiredTags(ctx context.Context, tags ...string) context.Context {
+	hints := HintsFromContext(ctx).clone()
+	hints.Tags = append(hints.Tags, tags...)
+	return WithHints(ctx, hints)
+}
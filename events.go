@@ -0,0 +1,148 @@
+package balancer
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventType is the type of Event.
+type EventType int
+
+const (
+	// EventBackendAdded is emitted when a backend is added to a balancer.
+	EventBackendAdded EventType = iota
+	// EventBackendRemoved is emitted when a backend is deleted from a balancer.
+	EventBackendRemoved
+	// EventBackendDead is emitted when an alive backend is marked as dead.
+	EventBackendDead
+	// EventBackendAlive is emitted when a dead backend is alive again.
+	EventBackendAlive
+	// EventBackendEjected is emitted when a backend is ejected by the outlier detection.
+	EventBackendEjected
+	// EventBackendReadmitted is emitted when an ejected backend is readmitted.
+	EventBackendReadmitted
+	// EventCircuitOpened is emitted when the circuit breaker of a backend opens.
+	EventCircuitOpened
+	// EventCircuitClosed is emitted when the circuit breaker of a backend closes again.
+	EventCircuitClosed
+	// EventProbeFailed is emitted when a ping of the doctor fails.
+	EventProbeFailed
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventBackendAdded:
+		return "added"
+	case EventBackendRemoved:
+		return "removed"
+	case EventBackendDead:
+		return "dead"
+	case EventBackendAlive:
+		return "alive"
+	case EventBackendEjected:
+		return "ejected"
+	case EventBackendReadmitted:
+		return "readmitted"
+	case EventCircuitOpened:
+		return "circuit-opened"
+	case EventCircuitClosed:
+		return "circuit-closed"
+	case EventProbeFailed:
+		return "probe-failed"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a change of a backend.
+type Event struct {
+	Type     EventType `json:"type"`
+	Balancer string    `json:"balancer"`
+	Backend  string    `json:"backend"`
+	Reason   string    `json:"reason"`
+	Time     time.Time `json:"time"`
+}
+
+// Subscription receives the events from C, the events are dropped when C is full.
+type Subscription struct {
+	C       <-chan Event
+	ch      chan Event
+	types   map[EventType]bool
+	dropped uint64
+}
+
+// Dropped return the number of events dropped because the channel is full
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// EventBus delivers the events to the subscriptions without blocking the publisher.
+type EventBus struct {
+	subs map[*Subscription]struct{}
+	mux  sync.RWMutex
+}
+
+// Events is the event bus of all the balancers.
+var Events = NewEventBus()
+
+// NewEventBus creates an EventBus.
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subs: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe subscribes the events of the types, all the events if no type is given.
+// The channel buffers size events, at least 1.
+func (e *EventBus) Subscribe(size int, types ...EventType) *Subscription {
+	if size < 1 {
+		size = 1
+	}
+
+	ch := make(chan Event, size)
+	sub := &Subscription{C: ch, ch: ch}
+	if len(types) > 0 {
+		sub.types = make(map[EventType]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.subs[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe removes the subscription and closes its channel.
+func (e *EventBus) Unsubscribe(sub *Subscription) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	if _, ok := e.subs[sub]; ok {
+		delete(e.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Publish delivers the event to the subscriptions, the time is set if it is zero.
+func (e *EventBus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+
+	for sub := range e.subs {
+		if sub.types != nil && !sub.types[event.Type] {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			atomic.AddUint64(&sub.dropped, 1)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package balancer
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// DefaultReplicas is the default number of virtual nodes of a backend on the hash ring.
+const DefaultReplicas = 160
+
+// HashRing is a consistent hash ring with virtual nodes.
+type HashRing struct {
+	points []uint64
+	nodes  map[uint64]*Backend
+}
+
+// NewHashRing creates a hash ring of all the backends, including the dead ones,
+// so that the mappings of the other backends do not change when a backend is dead.
+func NewHashRing(backends *Backends, replicas int) *HashRing {
+	if replicas <= 0 {
+		replicas = DefaultReplicas
+	}
+
+	r := &HashRing{
+		points: make([]uint64, 0, backends.Len()*replicas),
+		nodes:  make(map[uint64]*Backend),
+	}
+
+	backends.Range(func(index int, backend *Backend) bool {
+		for i := 0; i < replicas; i++ {
+			point := hashKey(backend.URL + "#" + strconv.Itoa(i))
+			if _, ok := r.nodes[point]; ok {
+				continue
+			}
+			r.nodes[point] = backend
+			r.points = append(r.points, point)
+		}
+		return true
+	})
+
+	sort.Slice(r.points, func(i, j int) bool {
+		return r.points[i] < r.points[j]
+	})
+	return r
+}
+
+// Get returns at most n distinct available backends of the key, walking the ring clockwise.
+func (r *HashRing) Get(key string, n int) []*Backend {
+	length := len(r.points)
+	if length == 0 || n <= 0 {
+		return nil
+	}
+
+	hash := hashKey(key)
+	start := sort.Search(length, func(i int) bool {
+		return r.points[i] >= hash
+	})
+
+	result := make([]*Backend, 0, n)
+	seen := make(map[string]struct{})
+	for i := 0; i < length && len(result) < n; i++ {
+		backend := r.nodes[r.points[(start+i)%length]]
+		if _, ok := seen[backend.URL]; ok {
+			continue
+		}
+		seen[backend.URL] = struct{}{}
+		if backend.Available() {
+			result = append(result, backend)
+		}
+	}
+
+	return result
+}
+
+// ConsistentHash picks backends by key, the hash ring is rebuilt when the backends change.
+type ConsistentHash struct {
+	backends *Backends
+	replicas int
+	ring     *HashRing
+	version  uint64
+	mux      sync.Mutex
+}
+
+// NewConsistentHash creates a ConsistentHash of the backends.
+func NewConsistentHash(backends *Backends, replicas int) *ConsistentHash {
+	return &ConsistentHash{
+		backends: backends,
+		replicas: replicas,
+	}
+}
+
+// PickByKey returns n distinct available backends for the key, fewer if there are not enough available backends.
+func (c *ConsistentHash) PickByKey(key string, n int) ([]*Backend, error) {
+	backends := c.hashRing().Get(key, n)
+	if len(backends) == 0 {
+		return nil, ErrNoBackendAvailable
+	}
+	return backends, nil
+}
+
+func (c *ConsistentHash) hashRing() *HashRing {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	version := c.backends.Version()
+	if c.ring == nil || c.version != version {
+		c.ring = NewHashRing(c.backends, c.replicas)
+		c.version = version
+	}
+	return c.ring
+}
+
+// hashKey hashes the key by FNV-64a, the result is mixed by the splitmix64 finalizer,
+// since the FNV hashes of the similar keys like the points of a backend are clustered on the ring.
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
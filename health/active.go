@@ -0,0 +1,77 @@
+package health
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/bytom/blockcenter/balancer"
+)
+
+// ActiveName is the name of the active doctor.
+const ActiveName = "Active"
+
+func init() {
+	Register(newActiveBuilder())
+}
+
+type activeBuilder struct {
+	name string
+}
+
+func newActiveBuilder() balancer.DoctorBuilder {
+	return &activeBuilder{
+		name: ActiveName,
+	}
+}
+
+func (a *activeBuilder) Build(ping balancer.PingHandler, backends *balancer.Backends, opts *balancer.DoctorOptions) balancer.Doctor {
+	return &activeDoctor{
+		doctor: doctor{
+			backends: backends,
+			ping:     ping,
+		},
+		healthy:   opts.HealthyThreshold(),
+		unhealthy: opts.UnhealthyThreshold(),
+	}
+}
+
+func (a *activeBuilder) Name() string {
+	return a.name
+}
+
+// activeDoctor pings all the backends once each time, including the alive ones.
+// An alive backend is dead after the unhealthy threshold of consecutive failed pings,
+// and a dead backend is alive again after the healthy threshold of consecutive successful pings.
+type activeDoctor struct {
+	doctor
+	healthy   int
+	unhealthy int
+}
+
+func (d *activeDoctor) HealthCheck() {
+	var wg sync.WaitGroup
+
+	d.backends.Range(func(index int, backend *balancer.Backend) bool {
+		wg.Add(1)
+		go func(backend *balancer.Backend) {
+			defer wg.Done()
+			d.Check(backend)
+		}(backend)
+		return true
+	})
+
+	wg.Wait()
+}
+
+// Check pings the backend once, and changes its state by the thresholds.
+func (d *activeDoctor) Check(backend *balancer.Backend) {
+	success := d.Ping(backend) == nil
+	n := backend.State.AddProbe(success)
+
+	alive := backend.State.Alive()
+	if success && !alive && n >= d.healthy {
+		backend.State.SetAliveReason(true, fmt.Sprintf("%d consecutive successful pings", n))
+	} else if !success && alive && n >= d.unhealthy {
+		backend.State.SetAliveReason(false, fmt.Sprintf("%d consecutive failed pings", n))
+	}
+}
@@ -0,0 +1,241 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bytom/blockcenter/balancer"
+)
+
+// ChainName is the name of the chain height doctor.
+const ChainName = "ChainHeight"
+
+// chainClient is the http client to get the chain state.
+var chainClient = &http.Client{Timeout: 10 * time.Second}
+
+func init() {
+	Register(newChainBuilder())
+}
+
+type chainBuilder struct {
+	name string
+}
+
+func newChainBuilder() balancer.DoctorBuilder {
+	return &chainBuilder{
+		name: ChainName,
+	}
+}
+
+func (c *chainBuilder) Build(ping balancer.PingHandler, backends *balancer.Backends, opts *balancer.DoctorOptions) balancer.Doctor {
+	return &chainDoctor{
+		doctor: doctor{
+			backends: backends,
+			ping:     ping,
+		},
+		opts:      chainOptions(&opts.Chain),
+		healthy:   opts.HealthyThreshold(),
+		unhealthy: opts.UnhealthyThreshold(),
+	}
+}
+
+func (c *chainBuilder) Name() string {
+	return c.name
+}
+
+// chainOptions returns the chain options with the defaults, which are shared by the doctor and the probe.
+func chainOptions(opts *balancer.ChainOptions) balancer.ChainOptions {
+	chain := *opts
+	if chain.MaxLag == 0 {
+		chain.MaxLag = 10
+	}
+	if chain.InfoHandler == nil {
+		chain.InfoHandler = NetInfo
+	}
+	return chain
+}
+
+// chainDoctor compares the chain state of all the backends, a backend fails the check if it
+// fails the ping, is syncing, lags behind its highest block or the best backend, or is on another network.
+// Like the active doctor, an alive backend is dead after the unhealthy threshold of consecutive failed checks,
+// and a dead backend is alive again after the healthy threshold of consecutive successful checks.
+type chainDoctor struct {
+	// doctor is not embedded, so that the chain doctor is not a BackendDoctor checking the backends one by one
+	doctor    doctor
+	opts      balancer.ChainOptions
+	healthy   int
+	unhealthy int
+}
+
+type chainResult struct {
+	backend *balancer.Backend
+	info    *balancer.ChainInfo
+	err     error
+}
+
+func (d *chainDoctor) HealthCheck() {
+	results := make([]*chainResult, 0, d.doctor.backends.Len())
+	d.doctor.backends.Range(func(index int, backend *balancer.Backend) bool {
+		results = append(results, &chainResult{backend: backend})
+		return true
+	})
+
+	var wg sync.WaitGroup
+	for _, result := range results {
+		wg.Add(1)
+		go func(result *chainResult) {
+			defer wg.Done()
+			if result.err = d.doctor.call(result.backend); result.err == nil {
+				result.info, result.err = d.chainInfo(result.backend)
+			}
+		}(result)
+	}
+	wg.Wait()
+
+	network := d.opts.NetworkID
+	if len(network) == 0 {
+		network = majorityNetwork(results)
+	}
+
+	// the best block is of the backends on the network, so that another network does not fail them
+	var best uint64
+	for _, result := range results {
+		if result.err == nil && result.info.NetWorkID == network && result.info.CurrentBlock > best {
+			best = result.info.CurrentBlock
+		}
+	}
+
+	for _, result := range results {
+		err := result.err
+		if err == nil {
+			err = d.check(result.info, best, network)
+		}
+		d.record(result.backend, err)
+	}
+}
+
+func (d *chainDoctor) Ping(backend *balancer.Backend) error {
+	return d.doctor.Ping(backend)
+}
+
+// majorityNetwork returns the network of most backends, a tie is broken by the highest block
+// and then by the smallest network id.
+func majorityNetwork(results []*chainResult) string {
+	type vote struct {
+		nodes  int
+		height uint64
+	}
+	votes := make(map[string]*vote)
+	for _, result := range results {
+		if result.err != nil {
+			continue
+		}
+		v, ok := votes[result.info.NetWorkID]
+		if !ok {
+			v = &vote{}
+			votes[result.info.NetWorkID] = v
+		}
+		v.nodes++
+		if result.info.CurrentBlock > v.height {
+			v.height = result.info.CurrentBlock
+		}
+	}
+
+	var network string
+	var winner *vote
+	for id, v := range votes {
+		switch {
+		case winner == nil,
+			v.nodes > winner.nodes,
+			v.nodes == winner.nodes && v.height > winner.height,
+			v.nodes == winner.nodes && v.height == winner.height && id < network:
+			network, winner = id, v
+		}
+	}
+	return network
+}
+
+// record records the check of the backend, and changes its state by the thresholds.
+func (d *chainDoctor) record(backend *balancer.Backend, err error) {
+	success := err == nil
+	backend.Statistic.IncProbe(success)
+	if err != nil {
+		backend.Emit(balancer.EventProbeFailed, err.Error())
+	}
+	n := backend.State.AddProbe(success)
+
+	alive := backend.State.Alive()
+	if success && !alive && n >= d.healthy {
+		backend.State.SetAliveReason(true, fmt.Sprintf("%d consecutive successful chain checks", n))
+	} else if !success && alive && n >= d.unhealthy {
+		backend.State.SetAliveReason(false, fmt.Sprintf("%d consecutive failed chain checks: %v", n, err))
+	}
+}
+
+// check returns the reason why the node is unhealthy, or nil if it is healthy.
+func (d *chainDoctor) check(info *balancer.ChainInfo, best uint64, network string) error {
+	switch {
+	case info.NetWorkID != network:
+		return fmt.Errorf("network id %s, expected %s", info.NetWorkID, network)
+	case info.Syncing && !d.opts.AllowSyncing:
+		return errors.New("syncing")
+	case info.HighestBlock > info.CurrentBlock+d.opts.MaxLag:
+		return fmt.Errorf("current block %d, highest block %d", info.CurrentBlock, info.HighestBlock)
+	case best > info.CurrentBlock+d.opts.MaxLag:
+		return fmt.Errorf("current block %d, best block %d", info.CurrentBlock, best)
+	default:
+		return nil
+	}
+}
+
+func (d *chainDoctor) chainInfo(backend *balancer.Backend) (info *balancer.ChainInfo, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Doctor.ChainInfo(): %v", r)
+		}
+	}()
+
+	return d.opts.InfoHandler(backend)
+}
+
+// NetInfo gets the chain state of a Bytom or Vapor node by /net-info.
+func NetInfo(backend *balancer.Backend) (*balancer.ChainInfo, error) {
+	req, err := NewBackendRequest(backend, "POST", "/net-info", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := chainClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &struct {
+		Status    string             `json:"status"`
+		ErrDetail string             `json:"error_detail"`
+		Data      balancer.ChainInfo `json:"data"`
+	}{}
+	if err := json.Unmarshal(body, result); err != nil {
+		return nil, err
+	}
+	if result.Status != "success" {
+		return nil, errors.New(result.ErrDetail)
+	}
+
+	return &result.Data, nil
+}
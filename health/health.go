@@ -2,6 +2,7 @@ package health
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
@@ -44,10 +45,12 @@ func newBuilder() balancer.DoctorBuilder {
 	}
 }
 
-func (d *doctorBuilder) Build(ping balancer.PingHandler, backends *balancer.Backends) balancer.Doctor {
+func (d *doctorBuilder) Build(ping balancer.PingHandler, backends *balancer.Backends, opts *balancer.DoctorOptions) balancer.Doctor {
 	return &doctor{
 		backends: backends,
 		ping:     ping,
+		healthy:  opts.HealthyThreshold(),
+		interval: opts.PingInterval(),
 	}
 }
 
@@ -55,9 +58,13 @@ func (d *doctorBuilder) Name() string {
 	return d.name
 }
 
+// doctor pings the dead backends, a backend is alive again after the healthy
+// threshold of consecutive successful pings.
 type doctor struct {
 	backends *balancer.Backends
 	ping     balancer.PingHandler
+	healthy  int
+	interval time.Duration
 }
 
 func (d *doctor) HealthCheck() {
@@ -68,18 +75,7 @@ func (d *doctor) HealthCheck() {
 			wg.Add(1)
 			go func(backend *balancer.Backend) {
 				defer wg.Done()
-				var errnum int
-
-				for i := 0; i < 10; i++ {
-					if err := d.Ping(backend); err != nil {
-						errnum += 1
-					}
-					time.Sleep(10 * time.Second)
-				}
-
-				if errnum == 0 {
-					backend.State.SetAlive(true)
-				}
+				d.Check(backend)
 			}(backend)
 		}
 
@@ -89,7 +85,42 @@ func (d *doctor) HealthCheck() {
 	wg.Wait()
 }
 
-func (d *doctor) Ping(backend *balancer.Backend) (err error) {
+// Check pings the backend if it is dead, and revives it after the healthy threshold of successful pings.
+func (d *doctor) Check(backend *balancer.Backend) {
+	if backend.State.Alive() {
+		return
+	}
+
+	for i := 0; i < d.healthy; i++ {
+		if i > 0 {
+			time.Sleep(d.interval)
+		}
+		if err := d.Ping(backend); err != nil {
+			backend.State.AddProbe(false)
+			return
+		}
+		backend.State.AddProbe(true)
+	}
+
+	backend.State.SetAliveReason(true, fmt.Sprintf("%d consecutive successful pings", d.healthy))
+}
+
+// Ping pings the backend by the ping handler, and records the probe.
+func (d *doctor) Ping(backend *balancer.Backend) error {
+	if d.ping == nil {
+		return nil
+	}
+
+	err := d.call(backend)
+	backend.Statistic.IncProbe(err == nil)
+	if err != nil {
+		backend.Emit(balancer.EventProbeFailed, err.Error())
+	}
+	return err
+}
+
+// call calls the ping handler without recording the probe, a panic of the handler is returned as an error.
+func (d *doctor) call(backend *balancer.Backend) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			var str string
@@ -108,11 +139,23 @@ func (d *doctor) Ping(backend *balancer.Backend) (err error) {
 	if d.ping != nil {
 		return d.ping(backend)
 	}
-
 	return nil
 }
 
+// Done is the DoneHandler with the default failure thresholds.
 func Done(info balancer.DoneInfo) {
+	done(info, (&balancer.DoctorOptions{}).FailurePolicy())
+}
+
+// NewDoneHandler creates a DoneHandler with the failure thresholds of the options.
+func NewDoneHandler(opts *balancer.DoctorOptions) balancer.DoneHandler {
+	policy := opts.FailurePolicy()
+	return func(info balancer.DoneInfo) {
+		done(info, policy)
+	}
+}
+
+func done(info balancer.DoneInfo, policy balancer.FailurePolicy) {
 	backend := info.Backend
 	resp := info.Response
 	err := info.Error
@@ -126,11 +169,12 @@ func Done(info balancer.DoneInfo) {
 	} else if resp != nil && resp.StatusCode >= 400 {
 		e = errors.New(resp.Status)
 	} else {
+		backend.State.AddSuccess()
 		return
 	}
 
 	backend.State.AddFail(e)
 	if backend.State.Alive() {
-		backend.State.HealthCheck(600, 100) //10分钟内最多失败100次，超出后backend标记为不可用
+		backend.State.HealthCheck(policy)
 	}
 }
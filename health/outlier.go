@@ -0,0 +1,172 @@
+package health
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bytom/blockcenter/balancer"
+)
+
+// OutlierDetector ejects the backends which are up but fail much more than their peers,
+// either by consecutive errors or by a success rate far below the mean of the backends.
+type OutlierDetector struct {
+	opts     *balancer.OutlierOptions
+	backends *balancer.Backends
+	hosts    map[string]*outlierHost
+	mux      sync.Mutex
+}
+
+// outlierHost keeps the counters of the previous detection and the ejection multiplier.
+type outlierHost struct {
+	success    uint64
+	failure    uint64
+	multiplier int
+}
+
+// outlierRate is the success rate of a backend since the previous detection.
+type outlierRate struct {
+	backend *balancer.Backend
+	rate    float64
+}
+
+// NewOutlierDetector creates an OutlierDetector of the backends.
+func NewOutlierDetector(opts *balancer.OutlierOptions, backends *balancer.Backends) *OutlierDetector {
+	if len(opts.Spec) == 0 {
+		opts.Spec = "@every 10s"
+	}
+	if opts.ConsecutiveErrors <= 0 {
+		opts.ConsecutiveErrors = 5
+	}
+	if opts.SuccessRateStdev <= 0 {
+		opts.SuccessRateStdev = 1.9
+	}
+	if opts.MinHosts <= 0 {
+		opts.MinHosts = 5
+	}
+	if opts.MinRequests <= 0 {
+		opts.MinRequests = 100
+	}
+	if opts.MaxEjectionPercent <= 0 {
+		opts.MaxEjectionPercent = 10
+	}
+	if opts.BaseEjectionTime <= 0 {
+		opts.BaseEjectionTime = 30
+	}
+	if opts.MaxEjectionTime <= 0 {
+		opts.MaxEjectionTime = 300
+	}
+
+	return &OutlierDetector{
+		opts:     opts,
+		backends: backends,
+		hosts:    make(map[string]*outlierHost),
+	}
+}
+
+// Detect readmits the backends whose ejection time is over, and ejects the outliers.
+func (o *OutlierDetector) Detect() {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	now := time.Now()
+	nodes := make([]*balancer.Backend, 0, o.backends.Len())
+	o.backends.Range(func(index int, backend *balancer.Backend) bool {
+		nodes = append(nodes, backend)
+		return true
+	})
+
+	ejected := 0
+	rates := make([]outlierRate, 0, len(nodes))
+	hosts := make(map[string]*outlierHost, len(nodes))
+	for _, backend := range nodes {
+		host, ok := o.hosts[backend.URL]
+		if !ok {
+			host = &outlierHost{}
+		}
+		hosts[backend.URL] = host
+
+		if backend.State.Ejected() {
+			if now.Before(backend.State.EjectedUntil()) {
+				ejected++
+			} else {
+				backend.State.Readmit()
+			}
+		} else if host.multiplier > 0 {
+			host.multiplier--
+		}
+
+		success, failure := backend.Statistic.Success(), backend.Statistic.Failure()
+		if success < host.success || failure < host.failure {
+			// the statistics are reset
+			host.success, host.failure = 0, 0
+		}
+		ds, df := success-host.success, failure-host.failure
+		host.success, host.failure = success, failure
+		if ds+df >= uint64(o.opts.MinRequests) {
+			rates = append(rates, outlierRate{backend: backend, rate: float64(ds) / float64(ds+df)})
+		}
+	}
+	// forget the deleted backends
+	o.hosts = hosts
+
+	max := len(nodes) * o.opts.MaxEjectionPercent / 100
+	if max < 1 {
+		max = 1
+	}
+	eject := func(backend *balancer.Backend, reason string) {
+		if ejected >= max || backend.State.Ejected() {
+			return
+		}
+		host := o.hosts[backend.URL]
+		host.multiplier++
+		backend.State.EjectReason(now.Add(o.ejectionTime(host.multiplier)), reason)
+		backend.Statistic.ResetConsecutiveErrors()
+		ejected++
+	}
+
+	for _, backend := range nodes {
+		if n := backend.Statistic.ConsecutiveErrors(); n >= uint64(o.opts.ConsecutiveErrors) {
+			eject(backend, fmt.Sprintf("%d consecutive errors", n))
+		}
+	}
+
+	if len(rates) < o.opts.MinHosts {
+		return
+	}
+	var sum, squares float64
+	for _, r := range rates {
+		sum += r.rate
+	}
+	mean := sum / float64(len(rates))
+	for _, r := range rates {
+		squares += (r.rate - mean) * (r.rate - mean)
+	}
+	threshold := mean - o.opts.SuccessRateStdev*math.Sqrt(squares/float64(len(rates)))
+
+	// the worst backends are ejected first when the ejections are capped
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].rate < rates[j].rate
+	})
+	for _, r := range rates {
+		if r.rate >= threshold {
+			break
+		}
+		eject(r.backend, fmt.Sprintf("success rate %.3f below %.3f", r.rate, threshold))
+	}
+}
+
+// ejectionTime doubles the base ejection time each time the backend is ejected again.
+func (o *OutlierDetector) ejectionTime(multiplier int) time.Duration {
+	d := time.Duration(o.opts.BaseEjectionTime) * time.Second
+	max := time.Duration(o.opts.MaxEjectionTime) * time.Second
+	for i := 1; i < multiplier && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
@@ -0,0 +1,237 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bytom/blockcenter/balancer"
+)
+
+// Probe types of ProbeOptions.
+const (
+	ProbeHTTP  = "http"
+	ProbeTCP   = "tcp"
+	ProbeChain = "chain"
+)
+
+// Probe checks whether a backend is healthy.
+type Probe interface {
+	Probe(backend *balancer.Backend) error
+}
+
+// NewPingHandler creates the PingHandler of the probe selected by the options.
+func NewPingHandler(opts *balancer.DoctorOptions) (balancer.PingHandler, error) {
+	probe, err := NewProbe(opts)
+	if err != nil {
+		return nil, err
+	}
+	return probe.Probe, nil
+}
+
+// NewProbe creates the probe selected by the options.
+func NewProbe(opts *balancer.DoctorOptions) (Probe, error) {
+	timeout := time.Duration(opts.Probe.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	switch strings.ToLower(opts.Probe.Type) {
+	case "", ProbeHTTP:
+		return NewHTTPProbe(&opts.Probe, timeout)
+	case ProbeTCP:
+		return &TCPProbe{Timeout: timeout}, nil
+	case ProbeChain:
+		chain := chainOptions(&opts.Chain)
+		return &ChainProbe{Handler: chain.InfoHandler, AllowSyncing: chain.AllowSyncing, MaxLag: chain.MaxLag}, nil
+	default:
+		return nil, fmt.Errorf("unknown probe type: %s", opts.Probe.Type)
+	}
+}
+
+// HTTPProbe sends a request to the backend, and checks the status and the body of the response.
+type HTTPProbe struct {
+	Method    string
+	Path      string
+	Status    int
+	Body      *regexp.Regexp
+	JSONPath  string
+	JSONValue string
+	client    *http.Client
+}
+
+// NewHTTPProbe creates a HTTPProbe, the default is the /net-info of Bytom and Vapor nodes.
+func NewHTTPProbe(opts *balancer.ProbeOptions, timeout time.Duration) (*HTTPProbe, error) {
+	p := &HTTPProbe{
+		Method:    opts.Method,
+		Path:      opts.Path,
+		Status:    opts.Status,
+		JSONPath:  opts.JSONPath,
+		JSONValue: opts.JSONValue,
+		client:    &http.Client{Timeout: timeout},
+	}
+	if len(p.Method) == 0 {
+		p.Method = http.MethodPost
+	}
+	if len(p.Path) == 0 {
+		p.Path = "/net-info"
+		if len(p.JSONPath) == 0 {
+			p.JSONPath = "status"
+		}
+	}
+	if len(p.JSONPath) > 0 && len(p.JSONValue) == 0 {
+		p.JSONValue = "success"
+	}
+	if p.Status == 0 {
+		p.Status = http.StatusOK
+	}
+	if len(opts.Body) > 0 {
+		body, err := regexp.Compile(opts.Body)
+		if err != nil {
+			return nil, err
+		}
+		p.Body = body
+	}
+	return p, nil
+}
+
+func (p *HTTPProbe) Probe(backend *balancer.Backend) error {
+	req, err := NewBackendRequest(backend, p.Method, p.Path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != p.Status {
+		return fmt.Errorf("status %s, expected %d", resp.Status, p.Status)
+	}
+	if p.Body == nil && len(p.JSONPath) == 0 {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if p.Body != nil && !p.Body.Match(body) {
+		return fmt.Errorf("body does not match %s", p.Body)
+	}
+	if len(p.JSONPath) > 0 {
+		return checkJSON(body, p.JSONPath, p.JSONValue)
+	}
+	return nil
+}
+
+// checkJSON checks the value of the dot separated path, e.g. "data.peers.0.id".
+func checkJSON(body []byte, path, expected string) error {
+	var val interface{}
+	if err := json.Unmarshal(body, &val); err != nil {
+		return err
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch v := val.(type) {
+		case map[string]interface{}:
+			val = v[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return fmt.Errorf("json path %s not found", path)
+			}
+			val = v[i]
+		default:
+			return fmt.Errorf("json path %s not found", path)
+		}
+	}
+
+	if val == nil {
+		return fmt.Errorf("json path %s not found", path)
+	}
+	if actual := fmt.Sprint(val); actual != expected {
+		return fmt.Errorf("json path %s is %s, expected %s", path, actual, expected)
+	}
+	return nil
+}
+
+// TCPProbe connects to the host of the backend.
+type TCPProbe struct {
+	Timeout time.Duration
+}
+
+func (p *TCPProbe) Probe(backend *balancer.Backend) error {
+	target := backend.Target()
+	host := target.Host
+	if len(target.Port()) == 0 {
+		port := "80"
+		if target.Scheme == "https" {
+			port = "443"
+		}
+		host = net.JoinHostPort(target.Hostname(), port)
+	}
+
+	conn, err := net.DialTimeout("tcp", host, p.Timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// ChainProbe gets the chain state of the backend, the backend is unhealthy if
+// it is syncing or lags behind its highest block.
+type ChainProbe struct {
+	Handler      balancer.ChainInfoHandler
+	AllowSyncing bool
+	MaxLag       uint64
+}
+
+func (p *ChainProbe) Probe(backend *balancer.Backend) error {
+	info, err := p.Handler(backend)
+	if err != nil {
+		return err
+	}
+	if info.Syncing && !p.AllowSyncing {
+		return errors.New("syncing")
+	}
+	if info.HighestBlock > info.CurrentBlock+p.MaxLag {
+		return fmt.Errorf("current block %d, highest block %d", info.CurrentBlock, info.HighestBlock)
+	}
+	return nil
+}
+
+// BytomPing pings a Bytom or Vapor node by /net-info.
+func BytomPing(backend *balancer.Backend) error {
+	probe, _ := NewHTTPProbe(&balancer.ProbeOptions{}, 5*time.Second)
+	return probe.Probe(backend)
+}
+
+// NewBackendRequest creates a request sent to the backend directly, with the headers of the backend.
+func NewBackendRequest(backend *balancer.Backend, method, path string, body io.Reader) (*http.Request, error) {
+	target := *backend.Target()
+	target.Path = balancer.URLJoin(target.Path, path)
+	target.RawPath = ""
+
+	req, err := http.NewRequest(method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range backend.Header() {
+		req.Header[k] = v
+	}
+	return req, nil
+}
@@ -0,0 +1,198 @@
+package balancer
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LatencyBuckets are the upper bounds of the latency histogram buckets, the same as the
+// default buckets of Prometheus.
+var LatencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Histogram is a lock-free histogram of the latencies with the fixed LatencyBuckets.
+type Histogram struct {
+	counts [12]uint64 // the last one is +Inf
+	count  uint64
+	sum    int64
+}
+
+// Observe add a latency to the histogram
+func (h *Histogram) Observe(d time.Duration) {
+	i := 0
+	for i < len(LatencyBuckets) && d > LatencyBuckets[i] {
+		i++
+	}
+	atomic.AddUint64(&h.counts[i], 1)
+	atomic.AddInt64(&h.sum, int64(d))
+	atomic.AddUint64(&h.count, 1)
+}
+
+// Reset reset the histogram
+func (h *Histogram) Reset() {
+	for i := range h.counts {
+		atomic.StoreUint64(&h.counts[i], 0)
+	}
+	atomic.StoreUint64(&h.count, 0)
+	atomic.StoreInt64(&h.sum, 0)
+}
+
+// Buckets return the count of each bucket, not cumulative, the last one is +Inf
+func (h *Histogram) Buckets() []uint64 {
+	counts := make([]uint64, len(h.counts))
+	for i := range h.counts {
+		counts[i] = atomic.LoadUint64(&h.counts[i])
+	}
+	return counts
+}
+
+// Count return the number of the latencies
+func (h *Histogram) Count() uint64 {
+	return atomic.LoadUint64(&h.count)
+}
+
+// Sum return the sum of the latencies
+func (h *Histogram) Sum() time.Duration {
+	return time.Duration(atomic.LoadInt64(&h.sum))
+}
+
+// Quantile return the estimated latency of the quantile, e.g. 0.99, by the linear interpolation
+// in the bucket, the latencies in the +Inf bucket are estimated as the largest bound
+func (h *Histogram) Quantile(q float64) time.Duration {
+	return quantile(h.Buckets(), q)
+}
+
+func quantile(counts []uint64, q float64) time.Duration {
+	var total uint64
+	for _, n := range counts {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := q * float64(total)
+	var cumulative uint64
+	for i, n := range counts {
+		if n == 0 || float64(cumulative+n) < rank {
+			cumulative += n
+			continue
+		}
+		if i >= len(LatencyBuckets) {
+			return LatencyBuckets[len(LatencyBuckets)-1]
+		}
+
+		var lower time.Duration
+		if i > 0 {
+			lower = LatencyBuckets[i-1]
+		}
+		upper := LatencyBuckets[i]
+		return lower + time.Duration(float64(upper-lower)*(rank-float64(cumulative))/float64(n))
+	}
+	return LatencyBuckets[len(LatencyBuckets)-1]
+}
+
+// RollingWindow counts the requests and the latencies of the last 15 minutes by minute.
+type RollingWindow struct {
+	slots [15]rollingSlot
+	mux   sync.Mutex
+}
+
+type rollingSlot struct {
+	minute   int64
+	requests uint64
+	failures uint64
+	counts   [12]uint64
+}
+
+// WindowStats is the statistics of the requests in a rolling window.
+type WindowStats struct {
+	Requests uint64        `json:"requests"`
+	Failures uint64        `json:"failures"`
+	P50      time.Duration `json:"p50"`
+	P95      time.Duration `json:"p95"`
+	P99      time.Duration `json:"p99"`
+}
+
+// slot returns the slot of the minute, the slot of 15 minutes ago is reused.
+func (r *RollingWindow) slot(now time.Time) *rollingSlot {
+	minute := now.Unix() / 60
+	s := &r.slots[minute%int64(len(r.slots))]
+	if s.minute != minute {
+		*s = rollingSlot{minute: minute}
+	}
+	return s
+}
+
+// Add add a request, failed is true if it is an error or its status is 4xx or 5xx
+func (r *RollingWindow) Add(failed bool) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	s := r.slot(time.Now())
+	s.requests++
+	if failed {
+		s.failures++
+	}
+}
+
+// Observe add a latency
+func (r *RollingWindow) Observe(d time.Duration) {
+	i := 0
+	for i < len(LatencyBuckets) && d > LatencyBuckets[i] {
+		i++
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.slot(time.Now()).counts[i]++
+}
+
+// Reset reset the window
+func (r *RollingWindow) Reset() {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.slots = [15]rollingSlot{}
+}
+
+// Stats return the statistics of the last minutes, from 1 to 15
+func (r *RollingWindow) Stats(minutes int) WindowStats {
+	if minutes < 1 {
+		minutes = 1
+	} else if minutes > len(r.slots) {
+		minutes = len(r.slots)
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	var stats WindowStats
+	counts := make([]uint64, len(LatencyBuckets)+1)
+	now := time.Now().Unix() / 60
+	for i := range r.slots {
+		s := &r.slots[i]
+		if s.minute <= now-int64(minutes) || s.minute > now {
+			continue
+		}
+		stats.Requests += s.requests
+		stats.Failures += s.failures
+		for j, n := range s.counts {
+			counts[j] += n
+		}
+	}
+
+	stats.P50, stats.P95, stats.P99 = quantile(counts, 0.5), quantile(counts, 0.95), quantile(counts, 0.99)
+	return stats
+}
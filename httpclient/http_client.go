@@ -2,6 +2,7 @@ package httpclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
@@ -25,43 +26,57 @@ func New(opts balancer.Options) (*HttpClient, error) {
 }
 
 func (h *HttpClient) Get(url string, result interface{}) error {
-	return h.request("GET", url, nil, nil, result)
+	return h.GetCtx(context.Background(), url, result)
+}
+
+func (h *HttpClient) GetCtx(ctx context.Context, url string, result interface{}) error {
+	return h.request(ctx, "GET", url, nil, nil, result)
 }
 
 func (h *HttpClient) GetWithHeader(url string, header map[string]string, result interface{}) error {
-	return h.request("GET", url, header, nil, result)
+	return h.GetWithHeaderCtx(context.Background(), url, header, result)
+}
+
+func (h *HttpClient) GetWithHeaderCtx(ctx context.Context, url string, header map[string]string, result interface{}) error {
+	return h.request(ctx, "GET", url, header, nil, result)
 }
 
 func (h *HttpClient) Post(url string, payload []byte, result interface{}) error {
-	return h.request("POST", url, nil, payload, result)
+	return h.PostCtx(context.Background(), url, payload, result)
+}
+
+func (h *HttpClient) PostCtx(ctx context.Context, url string, payload []byte, result interface{}) error {
+	return h.request(ctx, "POST", url, nil, payload, result)
 }
 
 func (h *HttpClient) PostWithHeader(url string, header map[string]string, payload []byte, result interface{}) error {
-	return h.request("POST", url, header, payload, result)
+	return h.PostWithHeaderCtx(context.Background(), url, header, payload, result)
 }
 
+func (h *HttpClient) PostWithHeaderCtx(ctx context.Context, url string, header map[string]string, payload []byte, result interface{}) error {
+	return h.request(ctx, "POST", url, header, payload, result)
+}
+
+// Do sends the request by the balancer, failed requests are retried by the retry policy of the balancer.
 func (h *HttpClient) Do(req *http.Request) (*http.Response, error) {
-	resp, err := h.Balancer.Do(req)
-
-	//Failure retry
-	if err != nil || (resp != nil && resp.StatusCode >= 400) {
-		for i := 0; i < 3; i++ {
-			resp, err = h.Balancer.Do(req)
-			if err == nil && (resp != nil && resp.StatusCode < 400) { //success
-				return resp, err
-			}
-		}
-	}
+	return h.Balancer.Do(req)
+}
 
-	return resp, err
+// DoContext is like Do, the call is canceled when ctx is done.
+func (h *HttpClient) DoContext(ctx context.Context, req *http.Request) (*http.Response, error) {
+	return h.Balancer.DoContext(ctx, req)
 }
 
 func (h *HttpClient) Request(method, url string, header map[string]string, payload []byte, result interface{}) error {
-	return h.request(method, url, header, payload, result)
+	return h.RequestCtx(context.Background(), method, url, header, payload, result)
+}
+
+func (h *HttpClient) RequestCtx(ctx context.Context, method, url string, header map[string]string, payload []byte, result interface{}) error {
+	return h.request(ctx, method, url, header, payload, result)
 }
 
-func (h *HttpClient) request(method, url string, header map[string]string, payload []byte, result interface{}) error {
-	req, err := http.NewRequest(method, url, bytes.NewBuffer(payload))
+func (h *HttpClient) request(ctx context.Context, method, url string, header map[string]string, payload []byte, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(payload))
 	if err != nil {
 		return err
 	}
@@ -72,7 +87,7 @@ func (h *HttpClient) request(method, url string, header map[string]string, paylo
 		req.Header.Set(k, v)
 	}
 
-	resp, err := h.Do(req)
+	resp, err := h.DoContext(ctx, req)
 	if err != nil {
 		return err
 	}
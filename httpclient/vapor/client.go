@@ -1,8 +1,8 @@
 package vapor
 
 import (
+	"context"
 	"encoding/json"
-	"strings"
 	"sync"
 
 	"github.com/bytom/bytom/errors"
@@ -23,6 +23,9 @@ var vaporErrMap = map[string]error{
 	"BTM716": service.ErrInputUTXONotFound,
 }
 
+// vaporIdempotentPaths are the read-only APIs which are retried although they use POST.
+var vaporIdempotentPaths = []string{"/get-block-count", "/get-raw-block", "/net-info"}
+
 type Client struct {
 	httpclient.HttpClient
 	NetParam string
@@ -30,6 +33,10 @@ type Client struct {
 }
 
 func NewClient(opts balancer.Options) (*Client, error) {
+	if len(opts.Retry.Paths) == 0 {
+		opts.Retry.Paths = vaporIdempotentPaths
+	}
+
 	client, err := httpclient.New(opts)
 	if err != nil {
 		return nil, err
@@ -43,11 +50,16 @@ func NewClient(opts balancer.Options) (*Client, error) {
 
 // GetBlock
 func (c *Client) GetBlock(id interface{}) (protocol.WrapBlock, *bc.TransactionStatus, error) {
+	return c.GetBlockCtx(context.Background(), id)
+}
+
+// GetBlockCtx return block by specified block hash or height
+func (c *Client) GetBlockCtx(ctx context.Context, id interface{}) (protocol.WrapBlock, *bc.TransactionStatus, error) {
 	switch key := id.(type) {
 	case string:
-		return c.GetBlockByHash(key)
+		return c.GetBlockByHashCtx(ctx, key)
 	case uint64:
-		return c.GetBlockByHeight(key)
+		return c.GetBlockByHeightCtx(ctx, key)
 	default:
 		return nil, nil, errors.New("unknown block id type")
 	}
@@ -55,12 +67,22 @@ func (c *Client) GetBlock(id interface{}) (protocol.WrapBlock, *bc.TransactionSt
 
 // GetBlockByHash return block by specified block hash
 func (c *Client) GetBlockByHash(hash string) (protocol.WrapBlock, *bc.TransactionStatus, error) {
-	return c.getRawBlock(&getRawBlockReq{BlockHash: hash})
+	return c.GetBlockByHashCtx(context.Background(), hash)
+}
+
+// GetBlockByHashCtx return block by specified block hash
+func (c *Client) GetBlockByHashCtx(ctx context.Context, hash string) (protocol.WrapBlock, *bc.TransactionStatus, error) {
+	return c.getRawBlock(ctx, &getRawBlockReq{BlockHash: hash})
 }
 
 // GetBlockByHeight return block by specified block height
 func (c *Client) GetBlockByHeight(height uint64) (protocol.WrapBlock, *bc.TransactionStatus, error) {
-	return c.getRawBlock(&getRawBlockReq{BlockHeight: height})
+	return c.GetBlockByHeightCtx(context.Background(), height)
+}
+
+// GetBlockByHeightCtx return block by specified block height
+func (c *Client) GetBlockByHeightCtx(ctx context.Context, height uint64) (protocol.WrapBlock, *bc.TransactionStatus, error) {
+	return c.getRawBlock(ctx, &getRawBlockReq{BlockHeight: height})
 }
 
 type getBlockCountResp struct {
@@ -69,9 +91,17 @@ type getBlockCountResp struct {
 
 // GetBlockCount return the best block height of connected node
 func (c *Client) GetBlockCount() (uint64, error) {
+	return c.GetBlockCountCtx(context.Background())
+}
+
+// GetBlockCountCtx return the best block height of connected node
+func (c *Client) GetBlockCountCtx(ctx context.Context) (uint64, error) {
 	url := "/get-block-count"
 	res := &getBlockCountResp{}
-	return res.BlockCount, c.Request(url, nil, res)
+	if err := c.RequestCtx(ctx, url, nil, res); err != nil {
+		return 0, err
+	}
+	return res.BlockCount, nil
 }
 
 type submitTxReq struct {
@@ -84,6 +114,11 @@ type submitTxResp struct {
 
 // SubmitTx submit transaction to node
 func (c *Client) SubmitTx(tx interface{}) (string, error) {
+	return c.SubmitTxCtx(context.Background(), tx)
+}
+
+// SubmitTxCtx submit transaction to node, the requests to all the nodes are canceled when ctx is done
+func (c *Client) SubmitTxCtx(ctx context.Context, tx interface{}) (string, error) {
 	url := "/submit-transaction"
 	payload, err := json.Marshal(submitTxReq{Tx: tx})
 	if err != nil {
@@ -105,46 +140,24 @@ func (c *Client) SubmitTx(tx interface{}) (string, error) {
 			group.Add(1)
 			go func(n int) {
 				ress[n] = &submitTxResp{}
-				errs[n] = c.Request(url, payload, ress[n])
+				errs[n] = c.RequestCtx(ctx, url, payload, ress[n])
 				group.Done()
 			}(i)
 		}
 	} else {
 		// Mapping addresses to 3 different nodes
-		codes := []int{balancer.HashCode(address + "1"), balancer.HashCode(address + "2"), balancer.HashCode(address + "3")}
-		urls := make([]string, 3)
-		backends := c.Balancer.Backends()
-
-		backends.RLock()
-		length := backends.Len()
-		for i, code := range codes {
-			n := code % length
-			backend, ok := backends.Get(n)
-			if !ok {
-				errs[i] = errors.New("cannot find node")
-			} else {
-				urls[i] = backend.URL
-			}
+		backends, err := c.Balancer.PickByKey(address, 3)
+		if err != nil {
+			return "", err
 		}
-		backends.RUnlock()
-
-		for i, u := range urls {
-			if len(u) == 0 {
-				continue
-			}
-
-			if strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") {
-				urls[i] = balancer.URLJoin(u, url)
-			} else {
-				urls[i] = balancer.URLJoin("http://", u, url)
-			}
 
+		for i, backend := range backends {
 			group.Add(1)
-			go func(n int) {
+			go func(n int, backend *balancer.Backend) {
 				ress[n] = &submitTxResp{}
-				errs[n] = c.Request(urls[n], payload, ress[n])
+				errs[n] = c.RequestCtx(balancer.WithPreferredBackend(ctx, backend.URL), url, payload, ress[n])
 				group.Done()
-			}(i)
+			}(i, backend)
 		}
 	}
 
@@ -166,8 +179,13 @@ type response struct {
 
 // Request send http request to node
 func (c *Client) Request(url string, payload []byte, respData interface{}) error {
+	return c.RequestCtx(context.Background(), url, payload, respData)
+}
+
+// RequestCtx send http request to node, the request is canceled when ctx is done
+func (c *Client) RequestCtx(ctx context.Context, url string, payload []byte, respData interface{}) error {
 	resp := &response{}
-	if err := c.Post(url, payload, resp); err != nil {
+	if err := c.PostCtx(ctx, url, payload, resp); err != nil {
 		return err
 	}
 
@@ -197,9 +215,14 @@ type NetInfo struct {
 
 // GetNodeInfo get node info
 func (c *Client) GetNodeInfo() (*NetInfo, error) {
+	return c.GetNodeInfoCtx(context.Background())
+}
+
+// GetNodeInfoCtx get node info
+func (c *Client) GetNodeInfoCtx(ctx context.Context) (*NetInfo, error) {
 	url := "/net-info"
 	res := &NetInfo{}
-	return res, c.Request(url, nil, res)
+	return res, c.RequestCtx(ctx, url, nil, res)
 }
 
 type getRawBlockReq struct {
@@ -212,7 +235,7 @@ type getRawBlockResp struct {
 	TransactionStatus *bc.TransactionStatus `json:"transaction_status"`
 }
 
-func (c *Client) getRawBlock(req *getRawBlockReq) (protocol.WrapBlock, *bc.TransactionStatus, error) {
+func (c *Client) getRawBlock(ctx context.Context, req *getRawBlockReq) (protocol.WrapBlock, *bc.TransactionStatus, error) {
 	url := "/get-raw-block"
 	payload, err := json.Marshal(req)
 	if err != nil {
@@ -220,7 +243,7 @@ func (c *Client) getRawBlock(req *getRawBlockReq) (protocol.WrapBlock, *bc.Trans
 	}
 
 	res := &getRawBlockResp{}
-	if err := c.Request(url, payload, res); err != nil {
+	if err := c.RequestCtx(ctx, url, payload, res); err != nil {
 		return nil, nil, err
 	}
 
@@ -232,7 +255,8 @@ func (c *Client) getRawBlock(req *getRawBlockReq) (protocol.WrapBlock, *bc.Trans
 			val, ok := backend.Cache.Get(blockHash)
 			flag := val.(bool)
 			if !ok || !flag {
-				if result, err := c.submitBlock(&submitBlockReq{Block: res.RawBlock}); err != nil {
+				// the forwarding outlives the call, so it is not bound to ctx
+				if result, err := c.submitBlock(context.Background(), &submitBlockReq{Block: res.RawBlock}); err != nil {
 					//todo
 				} else {
 					backend.Cache.Add(blockHash, result)
@@ -250,7 +274,7 @@ type submitBlockReq struct {
 	Block *types.Block `json:"raw_block"`
 }
 
-func (c *Client) submitBlock(req *submitBlockReq) (bool, error) {
+func (c *Client) submitBlock(ctx context.Context, req *submitBlockReq) (bool, error) {
 	url := "/submit-block"
 	payload, err := json.Marshal(req)
 	if err != nil {
@@ -258,7 +282,7 @@ func (c *Client) submitBlock(req *submitBlockReq) (bool, error) {
 	}
 
 	var res bool
-	if err := c.Request(url, payload, &res); err != nil {
+	if err := c.RequestCtx(ctx, url, payload, &res); err != nil {
 		return false, err
 	}
 
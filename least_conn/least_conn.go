@@ -0,0 +1,68 @@
+package least_conn
+
+import (
+	"math/rand"
+
+	"github.com/bytom/blockcenter/balancer"
+	"github.com/bytom/blockcenter/balancer/base"
+)
+
+// Name is the name of LeastConn Builder.
+const Name = "LeastConn"
+
+func init() {
+	balancer.Register(newBuilder())
+}
+
+// newBuilder creates a new least connections balancer builder.
+func newBuilder() balancer.Builder {
+	return base.NewBalancerBuilder(Name, &lcPickerBuilder{})
+}
+
+type lcPickerBuilder struct{}
+
+func (*lcPickerBuilder) Build(backends *balancer.Backends) balancer.Picker {
+	return &lcPicker{
+		backends: backends,
+	}
+}
+
+// lcPicker picks the available backend with the fewest in-flight requests,
+// and breaks ties randomly.
+type lcPicker struct {
+	backends *balancer.Backends
+}
+
+func (p *lcPicker) Pick() (*balancer.Backend, error) {
+	return p.PickWithInfo(&balancer.PickInfo{})
+}
+
+func (p *lcPicker) PickWithInfo(info *balancer.PickInfo) (*balancer.Backend, error) {
+	var least []*balancer.Backend
+	var min int64
+
+	p.backends.Range(func(index int, backend *balancer.Backend) bool {
+		if !backend.Available() || !info.Allow(backend) {
+			return true
+		}
+
+		inflight := backend.InFlight()
+		switch {
+		case len(least) == 0 || inflight < min:
+			min = inflight
+			least = append(least[:0], backend)
+		case inflight == min:
+			least = append(least, backend)
+		}
+		return true
+	})
+
+	switch len(least) {
+	case 0:
+		return nil, balancer.ErrNoBackendAvailable
+	case 1:
+		return least[0], nil
+	default:
+		return least[rand.Intn(len(least))], nil
+	}
+}
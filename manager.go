@@ -10,11 +10,13 @@ import (
 
 type manager struct {
 	balancers *sync.Map
+	options   *sync.Map
 }
 
 // Manager is a struct map from name to balancer.
 var Manager = &manager{
 	balancers: new(sync.Map),
+	options:   new(sync.Map),
 }
 
 // Register registers the balancer to the balancer name
@@ -31,6 +33,13 @@ func (m *manager) Get(name string) Balancer {
 	return nil
 }
 
+// Range traverses the balancers by name, in no particular order
+func (m *manager) Range(f func(name string, b Balancer) bool) {
+	m.balancers.Range(func(key, value interface{}) bool {
+		return f(key.(string), value.(Balancer))
+	})
+}
+
 // Balancer get and create a balancer
 func (m *manager) Balancer(opts *Options) (Balancer, error) {
 	if val, ok := m.balancers.Load(strings.ToLower(opts.Name)); ok {
@@ -40,7 +49,8 @@ func (m *manager) Balancer(opts *Options) (Balancer, error) {
 	if opts.Timeout <= 0 {
 		opts.Timeout = 30
 	}
-	client := &http.Client{Timeout: time.Duration(opts.Timeout) * time.Second}
+	// the timeout is applied to each call by the balancer, so that a call can have its own deadline
+	client := &http.Client{}
 
 	builder := Get(opts.Type)
 	if builder == nil {
@@ -53,43 +63,119 @@ func (m *manager) Balancer(opts *Options) (Balancer, error) {
 	}
 
 	m.balancers.Store(strings.ToLower(opts.Name), loadBalancing)
+	m.options.Store(strings.ToLower(opts.Name), opts)
 	return loadBalancing, nil
 }
 
-// UpdateOptions update balancer config
+// AddBackend adds a node to the balancer with the options of the balancer, false if the node exists
+func (m *manager) AddBackend(name string, node BackendOptions) (bool, error) {
+	balancer := m.Get(name)
+	val, ok := m.options.Load(strings.ToLower(name))
+	if balancer == nil || !ok {
+		return false, fmt.Errorf("unknown balancer: %s", name)
+	}
+
+	backend := NewBackendWithOptions(node, val.(*Options))
+	backend.Warmup()
+	return balancer.Backends().Add(backend), nil
+}
+
+// RemoveBackend drains the node and deletes it from the balancer, false if the node does not exist
+// or it is already being removed
+func (m *manager) RemoveBackend(name string, url string) (bool, error) {
+	balancer := m.Get(name)
+	if balancer == nil {
+		return false, fmt.Errorf("unknown balancer: %s", name)
+	}
+
+	backend, ok := balancer.Backends().Get(url)
+	if !ok || !backend.startRemoval() {
+		return false, nil
+	}
+
+	timeout := DefaultDrainTimeout * time.Second
+	if val, ok := m.options.Load(strings.ToLower(name)); ok {
+		timeout = val.(*Options).drainTimeout()
+	}
+	backend.State.SetDraining(true)
+	drain(balancer.Backends(), backend, timeout)
+	return true, nil
+}
+
+// UpdateOptions update balancer config, the removed nodes are drained before they are deleted,
+// UpdateOptions waits for their requests in flight up to the drain timeout
 func (m *manager) UpdateOptions(optsArr []*Options) error {
+	var wg sync.WaitGroup
 	for _, opts := range optsArr {
 		balancer := m.Get(opts.Name)
 		if balancer == nil {
 			continue
 		}
+		m.options.Store(strings.ToLower(opts.Name), opts)
 
 		optsMap := make(map[string]*Backend)
+		added, removed := make([]*Backend, 0), make([]*Backend, 0)
 		backends := balancer.Backends()
 		backends.Lock()
-		// add new node
-		for _, url := range opts.Urls {
-			if len(url) == 0 {
+		// add new node, update the settings of the existing node
+		for _, node := range opts.Nodes() {
+			if backend, ok := backends.get(node.URL); ok {
+				if backend.cancelRemoval() {
+					// the node is added back while it is draining
+					backend.State.SetDraining(false)
+				}
+				backend.update(node)
+				optsMap[node.URL] = backend
 				continue
 			}
 
-			backend := NewBackend(url, opts.CacheSize)
-			optsMap[url] = backend
-
-			if _, ok := backends.get(url); !ok {
-				backends.add(backend)
+			backend := NewBackendWithOptions(node, opts)
+			backend.Warmup()
+			optsMap[node.URL] = backend
+			if backends.add(backend) {
+				added = append(added, backend)
 			}
 		}
 
-		// delete old node
-		for i := len(backends.nodes) - 1; i >= 0; i-- {
-			backend := backends.nodes[i]
-			if _, ok := optsMap[backend.URL]; !ok {
-				backends.delete(backend)
+		// drain old node, it is not picked any more
+		for _, backend := range backends.nodes {
+			if _, ok := optsMap[backend.URL]; !ok && backend.startRemoval() {
+				backend.State.SetDraining(true)
+				removed = append(removed, backend)
 			}
 		}
 		backends.Unlock()
+
+		// the watchers may lock the backends, so they are notified after unlock
+		for _, backend := range added {
+			backends.notify(backend, true)
+		}
+
+		timeout := opts.drainTimeout()
+		for _, backend := range removed {
+			wg.Add(1)
+			go func(backends *Backends, backend *Backend) {
+				defer wg.Done()
+				drain(backends, backend, timeout)
+			}(backends, backend)
+		}
 	}
+	wg.Wait()
 
 	return nil
 }
+
+// drain waits for the requests in flight to the draining node up to the timeout,
+// and deletes the node unless it is added back in the meantime.
+func drain(backends *Backends, backend *Backend, timeout time.Duration) {
+	backend.WaitIdle(timeout)
+
+	// the mark is checked with the lock, as UpdateOptions adds the node back with the lock
+	backends.Lock()
+	ok := backend.cancelRemoval() && backends.delete(backend)
+	backends.Unlock()
+
+	if ok {
+		backends.notify(backend, false)
+	}
+}
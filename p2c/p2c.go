@@ -0,0 +1,87 @@
+package p2c
+
+import (
+	"math/rand"
+
+	"github.com/bytom/blockcenter/balancer"
+	"github.com/bytom/blockcenter/balancer/base"
+)
+
+// Name is the name of P2C Builder.
+const Name = "P2C"
+
+func init() {
+	balancer.Register(newBuilder())
+}
+
+// newBuilder creates a new power-of-two-choices balancer builder.
+func newBuilder() balancer.Builder {
+	return base.NewBalancerBuilder(Name, &p2cPickerBuilder{})
+}
+
+type p2cPickerBuilder struct{}
+
+func (*p2cPickerBuilder) Build(backends *balancer.Backends) balancer.Picker {
+	return &p2cPicker{
+		backends: backends,
+	}
+}
+
+// p2cPicker samples two random available backends and picks the less loaded one.
+// It keeps no shared state, so the picker only holds the read lock of the backends.
+type p2cPicker struct {
+	backends *balancer.Backends
+}
+
+func (p *p2cPicker) Pick() (*balancer.Backend, error) {
+	return p.PickWithInfo(&balancer.PickInfo{})
+}
+
+func (p *p2cPicker) PickWithInfo(info *balancer.PickInfo) (*balancer.Backend, error) {
+	alive := make([]*balancer.Backend, 0, p.backends.Len())
+	p.backends.Range(func(index int, backend *balancer.Backend) bool {
+		if backend.Available() && info.Allow(backend) {
+			alive = append(alive, backend)
+		}
+		return true
+	})
+
+	switch n := len(alive); n {
+	case 0:
+		return nil, balancer.ErrNoBackendAvailable
+	case 1:
+		return alive[0], nil
+	default:
+		i := rand.Intn(n)
+		j := rand.Intn(n - 1)
+		if j >= i {
+			j++
+		}
+		return lessLoaded(alive[i], alive[j]), nil
+	}
+}
+
+// lessLoaded compares the in-flight requests first, and then the failure ratio.
+func lessLoaded(a, b *balancer.Backend) *balancer.Backend {
+	ia, ib := a.InFlight(), b.InFlight()
+	if ia != ib {
+		if ia < ib {
+			return a
+		}
+		return b
+	}
+
+	if failureRatio(b) < failureRatio(a) {
+		return b
+	}
+	return a
+}
+
+func failureRatio(backend *balancer.Backend) float64 {
+	failure := backend.Statistic.Failure()
+	total := backend.Statistic.Success() + failure
+	if total == 0 {
+		return 0
+	}
+	return float64(failure) / float64(total)
+}
@@ -0,0 +1,86 @@
+package peak_ewma
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/bytom/blockcenter/balancer"
+	"github.com/bytom/blockcenter/balancer/base"
+)
+
+// Name is the name of PeakEWMA Builder.
+const Name = "PeakEWMA"
+
+// penalty is the cost of a backend which has no latency sample but has
+// in-flight requests, so that only one probe is sent to a cold backend.
+const penalty = math.MaxInt64 >> 16
+
+func init() {
+	balancer.Register(newBuilder())
+}
+
+// newBuilder creates a new peak-EWMA balancer builder.
+func newBuilder() balancer.Builder {
+	return base.NewBalancerBuilder(Name, &ewmaPickerBuilder{})
+}
+
+type ewmaPickerBuilder struct{}
+
+func (*ewmaPickerBuilder) Build(backends *balancer.Backends) balancer.Picker {
+	return &ewmaPicker{
+		backends: backends,
+	}
+}
+
+// ewmaPicker picks the available backend with the lowest cost, the cost is the
+// decayed peak-EWMA latency multiplied by the number of in-flight requests plus one.
+type ewmaPicker struct {
+	backends *balancer.Backends
+}
+
+func (p *ewmaPicker) Pick() (*balancer.Backend, error) {
+	return p.PickWithInfo(&balancer.PickInfo{})
+}
+
+func (p *ewmaPicker) PickWithInfo(info *balancer.PickInfo) (*balancer.Backend, error) {
+	var best []*balancer.Backend
+	var min float64
+
+	p.backends.Range(func(index int, backend *balancer.Backend) bool {
+		if !backend.Available() || !info.Allow(backend) {
+			return true
+		}
+
+		c := cost(backend)
+		switch {
+		case len(best) == 0 || c < min:
+			min = c
+			best = append(best[:0], backend)
+		case c == min:
+			best = append(best, backend)
+		}
+		return true
+	})
+
+	switch len(best) {
+	case 0:
+		return nil, balancer.ErrNoBackendAvailable
+	case 1:
+		return best[0], nil
+	default:
+		return best[rand.Intn(len(best))], nil
+	}
+}
+
+func cost(backend *balancer.Backend) float64 {
+	inflight := backend.InFlight()
+	latency, ok := backend.Statistic.Latency()
+	if !ok {
+		// cold start: probe the backend which has not been sampled yet
+		if inflight == 0 {
+			return 0
+		}
+		return penalty + float64(inflight)
+	}
+	return float64(latency) * float64(inflight+1)
+}
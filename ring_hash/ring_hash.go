@@ -0,0 +1,55 @@
+package ring_hash
+
+import (
+	"math/rand"
+
+	"github.com/bytom/blockcenter/balancer"
+	"github.com/bytom/blockcenter/balancer/base"
+)
+
+// Name is the name of RingHash Builder.
+const Name = "RingHash"
+
+func init() {
+	balancer.Register(newBuilder())
+}
+
+// newBuilder creates a new ring hash balancer builder.
+func newBuilder() balancer.Builder {
+	return base.NewBalancerBuilder(Name, &rhPickerBuilder{})
+}
+
+type rhPickerBuilder struct{}
+
+func (*rhPickerBuilder) Build(backends *balancer.Backends) balancer.Picker {
+	return &rhPicker{
+		backends:       backends,
+		ConsistentHash: balancer.NewConsistentHash(backends, balancer.DefaultReplicas),
+	}
+}
+
+// rhPicker maps a key to the backends on a consistent hash ring with virtual nodes,
+// a request without key is sent to a random available backend.
+type rhPicker struct {
+	*balancer.ConsistentHash
+	backends *balancer.Backends
+}
+
+func (p *rhPicker) Pick() (*balancer.Backend, error) {
+	return p.PickWithInfo(&balancer.PickInfo{})
+}
+
+func (p *rhPicker) PickWithInfo(info *balancer.PickInfo) (*balancer.Backend, error) {
+	alive := make([]*balancer.Backend, 0, p.backends.Len())
+	p.backends.Range(func(index int, backend *balancer.Backend) bool {
+		if backend.Available() && info.Allow(backend) {
+			alive = append(alive, backend)
+		}
+		return true
+	})
+
+	if len(alive) == 0 {
+		return nil, balancer.ErrNoBackendAvailable
+	}
+	return alive[rand.Intn(len(alive))], nil
+}
@@ -1,7 +1,6 @@
 package round_robin
 
 import (
-	"errors"
 	"github.com/bytom/blockcenter/balancer"
 	"github.com/bytom/blockcenter/balancer/base"
 )
@@ -40,11 +39,11 @@ func (p *rrPicker) Pick() (*balancer.Backend, error) {
 	l := next + length
 	for i := next; i < l; i++ {
 		idx := i % length
-		if backend, ok := p.backends.Get(idx); ok && backend.State.Alive() {
+		if backend, ok := p.backends.Get(idx); ok && backend.Available() {
 			p.current = idx
 			return backend, nil
 		}
 	}
 
-	return nil, errors.New("Picker.Pick(): No Backend available")
+	return nil, balancer.ErrNoBackendAvailable
 }
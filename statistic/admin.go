@@ -0,0 +1,250 @@
+package statistic
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytom/blockcenter/balancer"
+)
+
+// MaxAuditEntries is the number of the audit entries kept in memory.
+const MaxAuditEntries = 1000
+
+// AuditEntry records a change made by the admin api.
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	Remote   string    `json:"remote"`
+	Action   string    `json:"action"`
+	Balancer string    `json:"balancer"`
+	Backend  string    `json:"backend"`
+	Detail   string    `json:"detail,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+var (
+	audits    = make([]AuditEntry, 0)
+	auditsMux sync.RWMutex
+)
+
+// AuditLog returns the audit entries of the admin api, the oldest first.
+func AuditLog() []AuditEntry {
+	auditsMux.RLock()
+	defer auditsMux.RUnlock()
+	return append([]AuditEntry{}, audits...)
+}
+
+func audit(entry AuditEntry) {
+	auditsMux.Lock()
+	defer auditsMux.Unlock()
+
+	if len(audits) >= MaxAuditEntries {
+		audits = append(audits[:0], audits[len(audits)-MaxAuditEntries+1:]...)
+	}
+	audits = append(audits, entry)
+}
+
+// admin serves the admin api, the balancer and the backend are given by the name and url query parameters.
+type admin struct {
+	token string
+}
+
+func (a *admin) register(mux *http.ServeMux) {
+	mux.HandleFunc("/balancer/admin/balancers", a.auth(http.MethodGet, a.list))
+	mux.HandleFunc("/balancer/admin/audit", a.auth(http.MethodGet, a.audit))
+	mux.HandleFunc("/balancer/admin/backend/add", a.auth(http.MethodPost, a.change("add", a.add)))
+	mux.HandleFunc("/balancer/admin/backend/remove", a.auth(http.MethodPost, a.change("remove", a.remove)))
+	mux.HandleFunc("/balancer/admin/backend/drain", a.auth(http.MethodPost, a.change("drain", a.drain)))
+	mux.HandleFunc("/balancer/admin/backend/alive", a.auth(http.MethodPost, a.change("alive", a.alive)))
+	mux.HandleFunc("/balancer/admin/backend/reset", a.auth(http.MethodPost, a.change("reset", a.reset)))
+}
+
+// auth checks the method and the bearer token of the request.
+func (a *admin) auth(method string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(a.token) == 0 {
+			http.Error(w, "admin api is disabled", http.StatusForbidden)
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != method {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// change runs the change of the backend and records it in the audit log.
+func (a *admin) change(action string, fn func(r *http.Request, name, url string) (string, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name, url := r.URL.Query().Get("name"), r.URL.Query().Get("url")
+		var detail string
+		var err error
+		if len(name) == 0 || len(url) == 0 {
+			err = errors.New("name and url are required")
+		} else {
+			detail, err = fn(r, name, url)
+		}
+
+		entry := AuditEntry{
+			Time:     time.Now(),
+			Remote:   r.RemoteAddr,
+			Action:   action,
+			Balancer: name,
+			Backend:  url,
+			Detail:   detail,
+		}
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		audit(entry)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, entry)
+	}
+}
+
+func (a *admin) list(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0)
+	result := make(map[string]interface{})
+	balancer.Manager.Range(func(name string, lb balancer.Balancer) bool {
+		backends := make([]interface{}, 0)
+		lb.Backends().Range(func(index int, backend *balancer.Backend) bool {
+			backends = append(backends, map[string]interface{}{
+				"url":       backend.URL,
+				"weight":    backend.Weight(),
+				"alive":     backend.State.Alive(),
+				"held":      backend.State.Held(),
+				"ejected":   backend.State.Ejected(),
+				"draining":  backend.State.Draining(),
+				"in_flight": backend.InFlight(),
+			})
+			return true
+		})
+		names = append(names, name)
+		result[name] = backends
+		return true
+	})
+	sort.Strings(names)
+
+	list := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		list = append(list, map[string]interface{}{
+			"name":     name,
+			"backends": result[name],
+		})
+	}
+	writeJSON(w, list)
+}
+
+func (a *admin) audit(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, AuditLog())
+}
+
+func (a *admin) add(r *http.Request, name, url string) (string, error) {
+	node := balancer.BackendOptions{URL: url}
+	if weight := r.URL.Query().Get("weight"); len(weight) > 0 {
+		w, err := strconv.Atoi(weight)
+		if err != nil {
+			return "", fmt.Errorf("invalid weight: %s", weight)
+		}
+		node.Weight = w
+	}
+	if tags := r.URL.Query().Get("tags"); len(tags) > 0 {
+		node.Tags = strings.Split(tags, ",")
+	}
+
+	ok, err := balancer.Manager.AddBackend(name, node)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", errors.New("backend exists")
+	}
+	if node.Weight > 0 {
+		return fmt.Sprintf("weight %d", node.Weight), nil
+	}
+	return "", nil
+}
+
+func (a *admin) remove(r *http.Request, name, url string) (string, error) {
+	ok, err := balancer.Manager.RemoveBackend(name, url)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", errors.New("backend not found")
+	}
+	return "", nil
+}
+
+func (a *admin) drain(r *http.Request, name, url string) (string, error) {
+	backend, err := getBackend(name, url)
+	if err != nil {
+		return "", err
+	}
+	draining := r.URL.Query().Get("drain") != "false"
+	backend.State.SetDraining(draining)
+	return "draining " + strconv.FormatBool(draining), nil
+}
+
+func (a *admin) alive(r *http.Request, name, url string) (string, error) {
+	backend, err := getBackend(name, url)
+	if err != nil {
+		return "", err
+	}
+	alive, err := strconv.ParseBool(r.URL.Query().Get("alive"))
+	if err != nil {
+		return "", errors.New("alive must be true or false")
+	}
+	// a dead backend is held dead, so that the doctors do not make it alive until the admin api does
+	if alive {
+		backend.State.Unhold("admin api")
+	} else {
+		backend.State.Hold("admin api")
+	}
+	return "alive " + strconv.FormatBool(alive), nil
+}
+
+func (a *admin) reset(r *http.Request, name, url string) (string, error) {
+	backend, err := getBackend(name, url)
+	if err != nil {
+		return "", err
+	}
+	backend.Statistic.Reset()
+	return "", nil
+}
+
+func getBackend(name, url string) (*balancer.Backend, error) {
+	lb := balancer.Manager.Get(name)
+	if lb == nil {
+		return nil, fmt.Errorf("unknown balancer: %s", name)
+	}
+	backend, ok := lb.Backends().Get(url)
+	if !ok {
+		return nil, errors.New("backend not found")
+	}
+	return backend, nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Println(err)
+	}
+}
@@ -0,0 +1,133 @@
+package statistic
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bytom/blockcenter/balancer"
+)
+
+// statusClasses are the label values of the status classes of balancer.Statistic.Statuses.
+var statusClasses = []string{"error", "1xx", "2xx", "3xx", "4xx", "5xx"}
+
+// metricsHandler writes the metrics of all the balancers in the Prometheus text format.
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	buf := bufio.NewWriter(w)
+	writeMetrics(buf)
+	if err := buf.Flush(); err != nil {
+		fmt.Println(err)
+	}
+}
+
+type metricsBackend struct {
+	labels  string
+	backend *balancer.Backend
+}
+
+func writeMetrics(w *bufio.Writer) {
+	names := make([]string, 0)
+	lbs := make(map[string]balancer.Balancer)
+	balancer.Manager.Range(func(name string, lb balancer.Balancer) bool {
+		names = append(names, name)
+		lbs[name] = lb
+		return true
+	})
+	sort.Strings(names)
+
+	backends := make([]*metricsBackend, 0)
+	for _, name := range names {
+		lbs[name].Backends().Range(func(index int, backend *balancer.Backend) bool {
+			backends = append(backends, &metricsBackend{
+				labels:  fmt.Sprintf(`balancer="%s",backend="%s"`, escape(name), escape(backend.URL)),
+				backend: backend,
+			})
+			return true
+		})
+	}
+
+	header(w, "balancer_backend_up", "gauge", "Whether the backend is alive.")
+	for _, b := range backends {
+		sample(w, "balancer_backend_up", b.labels, boolValue(b.backend.State.Alive()))
+	}
+
+	header(w, "balancer_backend_ejected", "gauge", "Whether the backend is ejected by the outlier detection.")
+	for _, b := range backends {
+		sample(w, "balancer_backend_ejected", b.labels, boolValue(b.backend.State.Ejected()))
+	}
+
+	header(w, "balancer_backend_draining", "gauge", "Whether the backend is draining.")
+	for _, b := range backends {
+		sample(w, "balancer_backend_draining", b.labels, boolValue(b.backend.State.Draining()))
+	}
+
+	header(w, "balancer_in_flight_requests", "gauge", "Requests in flight to the backend.")
+	for _, b := range backends {
+		sample(w, "balancer_in_flight_requests", b.labels, strconv.FormatInt(b.backend.InFlight(), 10))
+	}
+
+	header(w, "balancer_requests_total", "counter", "Requests sent to the backend by status class, error is the requests without response.")
+	for _, b := range backends {
+		for class, n := range b.backend.Statistic.Statuses() {
+			sample(w, "balancer_requests_total", b.labels+`,class="`+statusClasses[class]+`"`, n)
+		}
+	}
+
+	header(w, "balancer_request_duration_seconds", "histogram", "Latency of the requests sent to the backend.")
+	for _, b := range backends {
+		histogram := b.backend.Statistic.Histogram()
+		var cumulative uint64
+		for i, n := range histogram.Buckets() {
+			cumulative += n
+			le := "+Inf"
+			if i < len(balancer.LatencyBuckets) {
+				le = strconv.FormatFloat(balancer.LatencyBuckets[i].Seconds(), 'g', -1, 64)
+			}
+			sample(w, "balancer_request_duration_seconds_bucket", b.labels+`,le="`+le+`"`, cumulative)
+		}
+		sample(w, "balancer_request_duration_seconds_sum", b.labels, strconv.FormatFloat(histogram.Sum().Seconds(), 'g', -1, 64))
+		sample(w, "balancer_request_duration_seconds_count", b.labels, histogram.Count())
+	}
+
+	header(w, "balancer_retries_total", "counter", "Retried requests sent to the backend.")
+	for _, b := range backends {
+		sample(w, "balancer_retries_total", b.labels, b.backend.Statistic.Retries())
+	}
+
+	header(w, "balancer_ejections_total", "counter", "Ejections of the backend by the outlier detection.")
+	for _, b := range backends {
+		sample(w, "balancer_ejections_total", b.labels, b.backend.Statistic.Ejections())
+	}
+
+	header(w, "balancer_probes_total", "counter", "Pings of the backend by the doctor.")
+	for _, b := range backends {
+		success, failure := b.backend.Statistic.Probes()
+		sample(w, "balancer_probes_total", b.labels+`,result="success"`, success)
+		sample(w, "balancer_probes_total", b.labels+`,result="failure"`, failure)
+	}
+}
+
+func header(w *bufio.Writer, name, typ, help string) {
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func sample(w *bufio.Writer, name, labels string, value interface{}) {
+	_, _ = fmt.Fprintf(w, "%s{%s} %v\n", name, labels, value)
+}
+
+func boolValue(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// escape escapes a label value of the Prometheus text format.
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
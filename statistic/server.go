@@ -1,21 +1,126 @@
 package statistic
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/bytom/blockcenter/balancer"
 )
 
-func ServerAndRun(statistic *balancer.StatisticOptions) {
+var (
+	servers    = make(map[int]*Server)
+	serversMux sync.Mutex
+)
+
+// Server is the admin server, it serves all the balancers of balancer.Manager.
+type Server struct {
+	port   int
+	token  string
+	server *http.Server
+}
+
+// Handler returns the handler of the admin endpoints, so that they can be mounted into
+// the mux of the application instead of starting a Server. The admin api is disabled.
+func Handler() http.Handler {
+	return NewHandler("")
+}
+
+// NewHandler returns the handler of the admin endpoints, the admin api is authenticated by
+// the bearer token, it is disabled if the token is empty.
+func NewHandler(token string) http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/balancer/statistic", indexHandler)
+	mux.HandleFunc("/metrics", metricsHandler)
+	(&admin{token: token}).register(mux)
+	return mux
+}
+
+// Start starts the admin server on the port without a token, the server is started once for each port
+// and shared by all the balancers.
+func Start(port int) (*Server, error) {
+	return StartWithOptions(&balancer.StatisticOptions{Port: port})
+}
+
+// StartWithOptions starts the admin server of the options, the balancers sharing the server
+// must have the same token, an error is returned if the token differs from the started server.
+func StartWithOptions(opts *balancer.StatisticOptions) (*Server, error) {
+	port := opts.Port
+	serversMux.Lock()
+	defer serversMux.Unlock()
 
-	addr := ":" + strconv.Itoa(statistic.Port)
-	if err := http.ListenAndServe(addr, mux); err != nil {
-		panic(err)
+	if s, ok := servers[port]; ok {
+		if s.token != opts.Token {
+			return nil, fmt.Errorf("admin server on port %d is started with another token", port)
+		}
+		return s, nil
+	}
+
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		port:   port,
+		token:  opts.Token,
+		server: &http.Server{Handler: NewHandler(opts.Token)},
+	}
+	servers[port] = s
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			fmt.Println(err)
+		}
+	}()
+	return s, nil
+}
+
+// Close shuts down the server gracefully, the requests in progress are waited for up to 5 seconds,
+// then the server is closed with the connections left.
+func (s *Server) Close() error {
+	serversMux.Lock()
+	if servers[s.port] == s {
+		delete(servers, s.port)
+	}
+	serversMux.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != context.DeadlineExceeded {
+		return err
+	}
+	return s.server.Close()
+}
+
+// Close shuts down all the started servers.
+func Close() error {
+	serversMux.Lock()
+	all := make([]*Server, 0, len(servers))
+	for _, s := range servers {
+		all = append(all, s)
+	}
+	serversMux.Unlock()
+
+	var err error
+	for _, s := range all {
+		if e := s.Close(); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// ServerAndRun starts the admin server of the options.
+//
+// Deprecated: use Start, which reports the error and returns the shared server.
+func ServerAndRun(statistic *balancer.StatisticOptions) {
+	if _, err := Start(statistic.Port); err != nil {
+		fmt.Println(err)
 	}
 }
 
@@ -35,8 +140,29 @@ func indexHandler(w http.ResponseWriter, r *http.Request) {
 			content := make(map[string]interface{})
 			content["url"] = url
 			content["alive"] = alive
+			content["draining"] = backend.State.Draining()
 			content["success"] = success
 			content["failure"] = failure
+			content["bytes_sent"] = backend.Statistic.BytesSent()
+			content["bytes_received"] = backend.Statistic.BytesReceived()
+
+			statuses := make(map[string]uint64)
+			for class, n := range backend.Statistic.Statuses() {
+				statuses[statusClasses[class]] = n
+			}
+			content["statuses"] = statuses
+
+			histogram := backend.Statistic.Histogram()
+			content["latency"] = latencyContent(histogram.Count(), histogram.Quantile(0.5), histogram.Quantile(0.95), histogram.Quantile(0.99))
+
+			windows := make(map[string]interface{})
+			for _, minutes := range []int{1, 5, 15} {
+				stats := backend.Statistic.Window(minutes)
+				window := latencyContent(stats.Requests, stats.P50, stats.P95, stats.P99)
+				window["failures"] = stats.Failures
+				windows[strconv.Itoa(minutes)+"m"] = window
+			}
+			content["windows"] = windows
 			result = append(result, content)
 			return true
 		})
@@ -54,3 +180,16 @@ func indexHandler(w http.ResponseWriter, r *http.Request) {
 		fmt.Println(err)
 	}
 }
+
+// latencyContent returns the percentiles of the latency in milliseconds.
+func latencyContent(requests uint64, p50, p95, p99 time.Duration) map[string]interface{} {
+	ms := func(d time.Duration) float64 {
+		return float64(d) / float64(time.Millisecond)
+	}
+	return map[string]interface{}{
+		"requests": requests,
+		"p50":      ms(p50),
+		"p95":      ms(p95),
+		"p99":      ms(p99),
+	}
+}
@@ -1,35 +1,76 @@
 package task
 
 import (
+	"sync"
+
 	"github.com/robfig/cron/v3"
 )
 
-var (
+// parser accepts the specs with or without the seconds field, e.g. "0 */1 * * * ?",
+// "*/1 * * * *" and "@every 10s".
+var parser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Scheduler runs the jobs by their specs, each balancer owns its scheduler,
+// and the jobs can be added and removed by name at runtime.
+type Scheduler struct {
 	crontab *cron.Cron
-)
+	entries map[string]cron.EntryID
+	mux     sync.Mutex
+}
 
-func Start(jobs ...*Job) error {
-	if len(jobs) == 0 {
-		return nil
+// NewScheduler creates a scheduler, the jobs start running after Start.
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		crontab: cron.New(cron.WithParser(parser), cron.WithChain(
+			cron.Recover(cron.DefaultLogger),
+		)),
+		entries: make(map[string]cron.EntryID),
 	}
+}
 
-	crontab = cron.New(cron.WithChain(
-		cron.Recover(cron.DefaultLogger),
-	))
+// Add adds the job by name, the job of the same name is replaced.
+func (s *Scheduler) Add(name string, job *Job) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
 
-	for _, job := range jobs {
-		eid, err := crontab.AddJob(job.spec, job)
-		if err != nil {
-			return err
-		}
+	eid, err := s.crontab.AddJob(job.spec, job)
+	if err != nil {
+		return err
+	}
 
-		cron.DefaultLogger.Info("Task EntryID: %d, %s\n", eid, job.spec)
+	if old, ok := s.entries[name]; ok {
+		s.crontab.Remove(old)
 	}
+	s.entries[name] = eid
 
-	crontab.Start()
+	cron.DefaultLogger.Info("Task EntryID: %d, %s, %s\n", eid, name, job.spec)
 	return nil
 }
 
-func Stop() {
-	<-crontab.Stop().Done()
+// Remove removes the job by name, the running job is not interrupted.
+func (s *Scheduler) Remove(name string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if eid, ok := s.entries[name]; ok {
+		s.crontab.Remove(eid)
+		delete(s.entries, name)
+	}
+}
+
+// Len returns the number of the jobs.
+func (s *Scheduler) Len() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return len(s.entries)
+}
+
+// Start starts the scheduler, it does nothing if the scheduler is already started.
+func (s *Scheduler) Start() {
+	s.crontab.Start()
+}
+
+// Stop stops the scheduler and waits for the running jobs.
+func (s *Scheduler) Stop() {
+	<-s.crontab.Stop().Done()
 }
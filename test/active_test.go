@@ -0,0 +1,51 @@
+package test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bytom/blockcenter/balancer"
+	"github.com/bytom/blockcenter/balancer/health"
+)
+
+func TestActiveDoctor(t *testing.T) {
+	var down int32
+	ping := func(backend *balancer.Backend) error {
+		if atomic.LoadInt32(&down) == 1 {
+			return errors.New("down")
+		}
+		return nil
+	}
+
+	backends := balancer.NewBackends()
+	backend := balancer.NewBackend("localhost:10000/api", 0)
+	backends.Add(backend)
+	doctor := health.Get("Active").Build(ping, backends, &balancer.DoctorOptions{Healthy: 2, Unhealthy: 3})
+
+	// an alive backend is dead after 3 consecutive failed pings
+	atomic.StoreInt32(&down, 1)
+	for i := 0; i < 2; i++ {
+		doctor.HealthCheck()
+		assert.True(t, backend.State.Alive(), i)
+	}
+	doctor.HealthCheck()
+	assert.False(t, backend.State.Alive())
+
+	// a success breaks the failures, and a dead backend is alive after 2 consecutive successful pings
+	atomic.StoreInt32(&down, 0)
+	doctor.HealthCheck()
+	assert.False(t, backend.State.Alive())
+	atomic.StoreInt32(&down, 1)
+	doctor.HealthCheck()
+	atomic.StoreInt32(&down, 0)
+	doctor.HealthCheck()
+	assert.False(t, backend.State.Alive())
+	doctor.HealthCheck()
+	assert.True(t, backend.State.Alive())
+	success, failure := backend.Statistic.Probes()
+	assert.Equal(t, uint64(3), success)
+	assert.Equal(t, uint64(4), failure)
+}
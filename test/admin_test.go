@@ -0,0 +1,164 @@
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bytom/blockcenter/balancer"
+	_ "github.com/bytom/blockcenter/balancer/round_robin"
+	"github.com/bytom/blockcenter/balancer/statistic"
+)
+
+func TestAdminServer(t *testing.T) {
+	// the balancers of the same port share the server
+	names := []string{
+		fmt.Sprintf("test-admin-1-%d", time.Now().UnixNano()),
+		fmt.Sprintf("test-admin-2-%d", time.Now().UnixNano()),
+	}
+	for _, name := range names {
+		opts := balancer.Options{
+			Name:      name,
+			Type:      "RoundRobin",
+			Urls:      []string{"localhost:10000/api1"},
+			Statistic: balancer.StatisticOptions{Enable: true, Port: 30002},
+		}
+		if _, err := balancer.Manager.Balancer(&opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := &http.Client{}
+	for _, name := range names {
+		resp, err := client.Get("http://localhost:30002/balancer/statistic?name=" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	server, err := statistic.Start(30002)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the idle connection of the client would hold the server until the timeout of Close
+	client.CloseIdleConnections()
+	assert.NoError(t, server.Close())
+	_, err = client.Get("http://localhost:30002/metrics")
+	assert.Error(t, err)
+
+	// the balancers of the same port must have the same token
+	server, err = statistic.StartWithOptions(&balancer.StatisticOptions{Port: 30004, Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	_, err = statistic.StartWithOptions(&balancer.StatisticOptions{Port: 30004, Token: "other"})
+	assert.Error(t, err)
+	_, err = statistic.Start(30004)
+	assert.Error(t, err)
+	shared, err := statistic.StartWithOptions(&balancer.StatisticOptions{Port: 30004, Token: "secret"})
+	assert.NoError(t, err)
+	assert.Equal(t, server, shared)
+
+	// the handler can be mounted into the mux of the application
+	mux := http.NewServeMux()
+	mux.Handle("/", statistic.Handler())
+	app := httptest.NewServer(mux)
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestAdminAPI(t *testing.T) {
+	name := fmt.Sprintf("test-admin-api-%d", time.Now().UnixNano())
+	opts := balancer.Options{
+		Name: name,
+		Type: "RoundRobin",
+		Urls: []string{"localhost:10000/api1", "localhost:10000/api2"},
+	}
+	lb, err := balancer.Manager.Balancer(&opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(statistic.NewHandler("secret"))
+	defer server.Close()
+
+	request := func(method, path, token string) int {
+		req, _ := http.NewRequest(method, server.URL+path, nil)
+		if len(token) > 0 {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, request("GET", "/balancer/admin/balancers", ""))
+	assert.Equal(t, http.StatusUnauthorized, request("GET", "/balancer/admin/balancers", "wrong"))
+	assert.Equal(t, http.StatusOK, request("GET", "/balancer/admin/balancers", "secret"))
+	assert.Equal(t, http.StatusMethodNotAllowed, request("GET", "/balancer/admin/backend/drain?name="+name+"&url=localhost:10000/api1", "secret"))
+
+	api1, _ := lb.Backends().Get("localhost:10000/api1")
+	assert.Equal(t, http.StatusOK, request("POST", "/balancer/admin/backend/drain?name="+name+"&url=localhost:10000/api1", "secret"))
+	assert.True(t, api1.State.Draining())
+	for i := 0; i < 4; i++ {
+		backend, err := lb.Pick()
+		assert.NoError(t, err)
+		assert.Equal(t, "localhost:10000/api2", backend.URL)
+	}
+
+	// the backend made dead by the admin api is not made alive by the doctors
+	assert.Equal(t, http.StatusOK, request("POST", "/balancer/admin/backend/alive?name="+name+"&url=localhost:10000/api1&alive=false", "secret"))
+	assert.False(t, api1.State.Alive())
+	api1.State.SetAliveReason(true, "doctor")
+	assert.False(t, api1.State.Alive())
+	assert.Equal(t, http.StatusOK, request("POST", "/balancer/admin/backend/alive?name="+name+"&url=localhost:10000/api1&alive=true", "secret"))
+	assert.True(t, api1.State.Alive())
+
+	api1.Statistic.IncSuccess()
+	assert.Equal(t, http.StatusOK, request("POST", "/balancer/admin/backend/reset?name="+name+"&url=localhost:10000/api1", "secret"))
+	assert.Equal(t, uint64(0), api1.Statistic.Success())
+
+	assert.Equal(t, http.StatusOK, request("POST", "/balancer/admin/backend/add?name="+name+"&url=localhost:10000/api3&weight=2", "secret"))
+	api3, ok := lb.Backends().Get("localhost:10000/api3")
+	assert.True(t, ok)
+	assert.Equal(t, 2, api3.Weight())
+	assert.Equal(t, http.StatusBadRequest, request("POST", "/balancer/admin/backend/add?name="+name+"&url=localhost:10000/api3", "secret"))
+
+	assert.Equal(t, http.StatusOK, request("POST", "/balancer/admin/backend/remove?name="+name+"&url=localhost:10000/api3", "secret"))
+	_, ok = lb.Backends().Get("localhost:10000/api3")
+	assert.False(t, ok)
+
+	actions := make([]string, 0)
+	for _, entry := range statistic.AuditLog() {
+		if entry.Balancer == name {
+			actions = append(actions, entry.Action)
+		}
+	}
+	assert.Equal(t, []string{"drain", "alive", "alive", "reset", "add", "add", "remove"}, actions)
+
+	// the admin api is disabled without a token
+	disabled := httptest.NewServer(statistic.Handler())
+	defer disabled.Close()
+	resp, err := http.Get(disabled.URL + "/balancer/admin/balancers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
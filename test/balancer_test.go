@@ -61,6 +61,10 @@ func TestBalancer(t *testing.T) {
 			Enable: true,
 			Port:   30000,
 		},
+		Retry: balancer.RetryOptions{
+			Backoff: 1,
+			Budget:  1,
+		},
 		Urls: []string{
 			"http://localhost:10000/api1",
 			"http://localhost:10000/api2",
@@ -0,0 +1,110 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bytom/blockcenter/balancer"
+	_ "github.com/bytom/blockcenter/balancer/round_robin"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	breaker := balancer.NewCircuitBreaker(balancer.BreakerOptions{
+		Enable:           true,
+		FailureRatio:     0.5,
+		MinRequests:      4,
+		OpenDuration:     1,
+		HalfOpenRequests: 2,
+	})
+	fail := func(n int) {
+		for i := 0; i < n; i++ {
+			assert.True(t, breaker.Allow())
+			breaker.Done(false)
+		}
+	}
+
+	// closed until the minimum requests
+	fail(3)
+	assert.Equal(t, balancer.BreakerClosed, breaker.State())
+	fail(1)
+	assert.Equal(t, balancer.BreakerOpen, breaker.State())
+	assert.False(t, breaker.Ready())
+	assert.False(t, breaker.Allow())
+
+	// half-open after the open duration, with limited trial requests
+	time.Sleep(1100 * time.Millisecond)
+	assert.Equal(t, balancer.BreakerHalfOpen, breaker.State())
+	assert.True(t, breaker.Allow())
+	assert.True(t, breaker.Allow())
+	assert.False(t, breaker.Ready())
+	assert.False(t, breaker.Allow())
+
+	// a released trial request can be taken again
+	breaker.Release()
+	assert.True(t, breaker.Ready())
+	assert.True(t, breaker.Allow())
+
+	// closed after the successful trial requests
+	breaker.Done(true)
+	assert.Equal(t, balancer.BreakerHalfOpen, breaker.State())
+	breaker.Done(true)
+	assert.Equal(t, balancer.BreakerClosed, breaker.State())
+
+	// open again when a trial request fails
+	fail(4)
+	time.Sleep(1100 * time.Millisecond)
+	assert.True(t, breaker.Allow())
+	breaker.Done(false)
+	assert.Equal(t, balancer.BreakerOpen, breaker.State())
+}
+
+func TestCircuitBreakerCanceledTrial(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fail":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/block":
+			<-r.Context().Done()
+		}
+	}))
+	defer server.Close()
+
+	lb := newBalancer(t, &balancer.Options{
+		Type:  "RoundRobin",
+		Urls:  []string{server.URL},
+		Retry: balancer.RetryOptions{MaxAttempts: 1},
+		Doctor: balancer.DoctorOptions{
+			Breaker: balancer.BreakerOptions{Enable: true, MinRequests: 2, OpenDuration: 1, HalfOpenRequests: 1},
+		},
+	})
+	backend, _ := lb.Backends().Get(server.URL)
+
+	for i := 0; i < 2; i++ {
+		get(t, lb, "/fail").Body.Close()
+	}
+	assert.Equal(t, balancer.BreakerOpen, backend.Breaker.State())
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	_, err := lb.Do(req)
+	assert.Equal(t, balancer.ErrNoBackendAvailable, err)
+
+	// the canceled trial request gives back its trial
+	time.Sleep(1100 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, "/block", nil)
+	_, err = lb.Do(req)
+	assert.True(t, errors.Is(err, context.Canceled), "%v", err)
+	assert.Equal(t, balancer.BreakerHalfOpen, backend.Breaker.State())
+	assert.True(t, backend.Breaker.Ready())
+
+	resp := get(t, lb, "/")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, balancer.BreakerClosed, backend.Breaker.State())
+}
@@ -0,0 +1,177 @@
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bytom/blockcenter/balancer"
+	"github.com/bytom/blockcenter/balancer/health"
+)
+
+// netInfoServer starts a node which responds the chain state by /net-info.
+func netInfoServer(syncing bool, current *uint64, highest uint64, network string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"status":"success","data":{"syncing":%t,"current_block":%d,"highest_block":%d,"network_id":"%s"}}`,
+			syncing, atomic.LoadUint64(current), highest, network)
+	}))
+}
+
+func block(height uint64) *uint64 {
+	return &height
+}
+
+func TestChainHeightDoctor(t *testing.T) {
+
+	lagging := block(900)
+	servers := map[string]*httptest.Server{
+		"best":    netInfoServer(false, block(1000), 1000, "mainnet"),
+		"near":    netInfoServer(false, block(995), 995, "mainnet"),
+		"lagging": netInfoServer(false, lagging, 900, "mainnet"),
+		"syncing": netInfoServer(true, block(1000), 1000, "mainnet"),
+		"testnet": netInfoServer(false, block(1000), 1000, "testnet"),
+	}
+	backends := balancer.NewBackends()
+	for _, server := range servers {
+		defer server.Close()
+		backends.Add(balancer.NewBackend(server.URL, 0))
+	}
+
+	doctor := health.Get("ChainHeight").Build(nil, backends, &balancer.DoctorOptions{Healthy: 3, Unhealthy: 2})
+
+	// a node is dead after 2 consecutive failed checks
+	doctor.HealthCheck()
+	for name, server := range servers {
+		backend, _ := backends.Get(server.URL)
+		assert.True(t, backend.State.Alive(), name)
+	}
+	doctor.HealthCheck()
+	for name, server := range servers {
+		backend, _ := backends.Get(server.URL)
+		assert.Equal(t, name == "best" || name == "near", backend.State.Alive(), name)
+	}
+
+	// the lagging node catches up, and is alive after 3 consecutive successful checks
+	atomic.StoreUint64(lagging, 1000)
+	backend, _ := backends.Get(servers["lagging"].URL)
+	for i := 0; i < 2; i++ {
+		doctor.HealthCheck()
+		assert.False(t, backend.State.Alive(), i)
+	}
+	doctor.HealthCheck()
+	assert.True(t, backend.State.Alive())
+}
+
+func TestChainHeightDoctorPing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"status":"success","data":{"syncing":false,"current_block":1000,"highest_block":1000,"network_id":"mainnet"}}`)
+	}))
+	defer server.Close()
+
+	backends := balancer.NewBackends()
+	backend := balancer.NewBackend(server.URL, 0)
+	backends.Add(backend)
+
+	// a node failing the ping is unhealthy although its chain state is good
+	ping := func(backend *balancer.Backend) error {
+		return fmt.Errorf("ping %s", backend.URL)
+	}
+	doctor := health.Get("ChainHeight").Build(ping, backends, &balancer.DoctorOptions{Unhealthy: 1})
+	doctor.HealthCheck()
+	assert.False(t, backend.State.Alive())
+
+	// a node killed by the failed requests is not alive again before the healthy threshold
+	backend.State.SetAliveReason(true, "")
+	doctor = health.Get("ChainHeight").Build(nil, backends, &balancer.DoctorOptions{Healthy: 2})
+	doctor.HealthCheck()
+	backend.State.SetAliveReason(false, "failed requests")
+	doctor.HealthCheck()
+	assert.False(t, backend.State.Alive())
+	doctor.HealthCheck()
+	assert.True(t, backend.State.Alive())
+}
+
+func TestChainHeightDoctorNetwork(t *testing.T) {
+	check := func(nodes map[string]*httptest.Server, alive ...string) {
+		backends := balancer.NewBackends()
+		for _, server := range nodes {
+			backends.Add(balancer.NewBackend(server.URL, 0))
+		}
+		doctor := health.Get("ChainHeight").Build(nil, backends, &balancer.DoctorOptions{Healthy: 1, Unhealthy: 1})
+		doctor.HealthCheck()
+
+		for name, server := range nodes {
+			backend, _ := backends.Get(server.URL)
+			expected := false
+			for _, a := range alive {
+				expected = expected || a == name
+			}
+			assert.Equal(t, expected, backend.State.Alive(), name)
+		}
+	}
+
+	servers := map[string]*httptest.Server{
+		"main1":   netInfoServer(false, block(1000), 1000, "mainnet"),
+		"main2":   netInfoServer(false, block(1000), 1000, "mainnet"),
+		"test":    netInfoServer(false, block(5000000), 5000000, "testnet"),
+		"solo":    netInfoServer(false, block(2000), 2000, "solonet"),
+		"another": netInfoServer(false, block(1000), 1000, "another"),
+	}
+	for _, server := range servers {
+		defer server.Close()
+	}
+
+	// the higher node on another network does not fail the nodes of the majority
+	check(map[string]*httptest.Server{"main1": servers["main1"], "main2": servers["main2"], "test": servers["test"]}, "main1", "main2")
+
+	// a tie is broken by the highest block, and then by the smallest network id
+	for i := 0; i < 10; i++ {
+		check(map[string]*httptest.Server{"main1": servers["main1"], "solo": servers["solo"]}, "solo")
+		check(map[string]*httptest.Server{"main1": servers["main1"], "another": servers["another"]}, "another")
+	}
+}
+
+func TestChainHeightDoctorScheduled(t *testing.T) {
+	best := netInfoServer(false, block(1000), 1000, "mainnet")
+	defer best.Close()
+	lagging := netInfoServer(false, block(900), 900, "mainnet")
+	defer lagging.Close()
+	unreachable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	unreachable.Close()
+
+	lb := newBalancer(t, &balancer.Options{
+		Type: "RoundRobin",
+		Urls: []string{best.URL, lagging.URL, unreachable.URL},
+		Doctor: balancer.DoctorOptions{
+			Enable:    true,
+			Type:      "ChainHeight",
+			Spec:      "@every 1s",
+			Healthy:   1,
+			Unhealthy: 1,
+		},
+	})
+	dead, _ := lb.Backends().Get(unreachable.URL)
+	dead.State.SetAlive(false)
+
+	// the scheduled checks compare the chain heights, and do not revive the unreachable node
+	time.Sleep(500 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		time.Sleep(time.Second)
+		for _, c := range []struct {
+			url   string
+			alive bool
+		}{
+			{best.URL, true},
+			{lagging.URL, false},
+			{unreachable.URL, false},
+		} {
+			backend, _ := lb.Backends().Get(c.url)
+			assert.Equal(t, c.alive, backend.State.Alive(), "tick %d: %s", i, c.url)
+		}
+	}
+}
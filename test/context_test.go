@@ -0,0 +1,139 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bytom/blockcenter/balancer"
+	"github.com/bytom/blockcenter/balancer/httpclient"
+	_ "github.com/bytom/blockcenter/balancer/round_robin"
+)
+
+// blockServer starts a server which holds the request before the response header
+// until the request is canceled, and counts the requests.
+func blockServer(t *testing.T, hits *int64) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(hits, 1)
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestContextCancel(t *testing.T) {
+	var hits int64
+	server := blockServer(t, &hits)
+	lb := newBalancer(t, &balancer.Options{Type: "RoundRobin", Urls: []string{server.URL}})
+
+	// the canceled context stops the pick
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	_, err := lb.DoContext(ctx, req)
+	assert.True(t, errors.Is(err, context.Canceled), "%v", err)
+	assert.Equal(t, int64(0), atomic.LoadInt64(&hits))
+
+	// and the request in flight
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	_, err = lb.DoContext(ctx, req)
+	assert.True(t, errors.Is(err, context.Canceled), "%v", err)
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&hits))
+
+	backend, _ := lb.Backends().Get(server.URL)
+	assert.Equal(t, int64(0), backend.InFlight())
+}
+
+func TestContextCancelBackoff(t *testing.T) {
+	var hits int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	lb := newBalancer(t, &balancer.Options{
+		Type:  "RoundRobin",
+		Urls:  []string{server.URL},
+		Retry: balancer.RetryOptions{MaxAttempts: 3, Backoff: 2000, MaxBackoff: 2000},
+	})
+
+	// the backoff between the attempts is stopped by the context
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+	start := time.Now()
+	resp, err := lb.Do(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&hits))
+}
+
+func TestDefaultTimeout(t *testing.T) {
+	var hits int64
+	blocked := blockServer(t, &hits)
+	held, _ := holdServer(t)
+
+	// the default timeout applies to the call without deadline
+	lb := newBalancer(t, &balancer.Options{Type: "RoundRobin", Timeout: 1, Urls: []string{blocked.URL}})
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	_, err := lb.Do(req)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "%v", err)
+
+	// the timeout is released when the body is closed, not when Do returns
+	lb = newBalancer(t, &balancer.Options{Type: "RoundRobin", Timeout: 1, Urls: []string{held.URL}})
+	resp := get(t, lb, "/")
+	ctx := resp.Request.Context()
+	_, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.NoError(t, ctx.Err())
+	resp.Body.Close()
+	assert.Equal(t, context.Canceled, ctx.Err())
+
+	// the deadline of the context is kept
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+	resp, err = lb.Do(req)
+	if assert.NoError(t, err) {
+		deadline, _ := resp.Request.Context().Deadline()
+		assert.True(t, time.Until(deadline) > 30*time.Second)
+		resp.Body.Close()
+	}
+}
+
+func TestHttpClientCtx(t *testing.T) {
+	var hits int64
+	server := blockServer(t, &hits)
+	client, err := httpclient.New(balancer.Options{
+		Name: fmt.Sprintf("test-client-ctx-%d", time.Now().UnixNano()),
+		Type: "RoundRobin",
+		Urls: []string{server.URL},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	result := make(map[string]interface{})
+	err = client.GetCtx(ctx, "/", &result)
+	assert.True(t, errors.Is(err, context.Canceled), "%v", err)
+	assert.True(t, time.Since(start) < time.Second)
+
+	err = client.PostCtx(ctx, "/", []byte("{}"), &result)
+	assert.True(t, errors.Is(err, context.Canceled), "%v", err)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&hits))
+}
@@ -0,0 +1,76 @@
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bytom/blockcenter/balancer"
+	_ "github.com/bytom/blockcenter/balancer/round_robin"
+)
+
+func TestDrain(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer fast.Close()
+
+	opts := balancer.Options{
+		Name:         fmt.Sprintf("test-drain-%d", time.Now().UnixNano()),
+		Type:         "RoundRobin",
+		Urls:         []string{slow.URL},
+		DrainTimeout: 5,
+	}
+	lb, err := balancer.Manager.Balancer(&opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a request in flight to the node which is removed
+	done := make(chan error)
+	go func() {
+		req, _ := http.NewRequest("GET", "/slow", nil)
+		resp, err := lb.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		done <- err
+	}()
+	for i := 0; i < 100; i++ {
+		if backend, _ := lb.Backends().Get(slow.URL); backend.InFlight() > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	updated := make(chan struct{})
+	go func() {
+		opts.Urls = []string{fast.URL}
+		assert.NoError(t, balancer.Manager.UpdateOptions([]*balancer.Options{&opts}))
+		close(updated)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	draining, ok := lb.Backends().Get(slow.URL)
+	assert.True(t, ok)
+	assert.True(t, draining.State.Draining())
+	for i := 0; i < 4; i++ {
+		backend, err := lb.Pick()
+		assert.NoError(t, err)
+		assert.Equal(t, fast.URL, backend.URL)
+	}
+
+	close(release)
+	assert.NoError(t, <-done)
+	<-updated
+	_, ok = lb.Backends().Get(slow.URL)
+	assert.False(t, ok)
+	assert.Equal(t, 1, lb.Backends().Len())
+}
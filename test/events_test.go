@@ -0,0 +1,75 @@
+package test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bytom/blockcenter/balancer"
+)
+
+func TestEvents(t *testing.T) {
+	// the bus is shared by the balancers of the other tests, only the events of this balancer are checked
+	all := balancer.Events.Subscribe(1024)
+	defer balancer.Events.Unsubscribe(all)
+
+	backends := balancer.NewBackends()
+	backend := balancer.NewBackendWithOptions(balancer.BackendOptions{URL: "http://127.0.0.1:9888"}, &balancer.Options{Name: "events"})
+	backends.Add(backend)
+	backend.State.AddFail(errors.New("failed"))
+	backend.State.HealthCheck(balancer.FailurePolicy{MaxFailures: 1})
+	backend.State.SetAlive(false)
+	backend.State.SetAlive(true)
+	backend.State.Eject(time.Now().Add(time.Minute))
+	backend.State.Readmit()
+	backends.Delete(backend)
+
+	expected := []balancer.EventType{
+		balancer.EventBackendAdded,
+		balancer.EventBackendDead,
+		balancer.EventBackendAlive,
+		balancer.EventBackendEjected,
+		balancer.EventBackendReadmitted,
+		balancer.EventBackendRemoved,
+	}
+	events := make([]balancer.Event, 0, len(expected))
+	timeout := time.After(time.Second)
+	for len(events) < len(expected) {
+		select {
+		case event := <-all.C:
+			if event.Balancer == "events" {
+				events = append(events, event)
+			}
+		case <-timeout:
+			t.Fatalf("%d of %d events received", len(events), len(expected))
+		}
+	}
+	for i, event := range events {
+		assert.Equal(t, expected[i], event.Type)
+		assert.Equal(t, backend.URL, event.Backend)
+		assert.False(t, event.Time.IsZero())
+	}
+	assert.Equal(t, "0 successes and 1 failures in the window", events[1].Reason)
+}
+
+func TestEventBus(t *testing.T) {
+	bus := balancer.NewEventBus()
+	small := bus.Subscribe(1, balancer.EventBackendDead, balancer.EventBackendAlive)
+
+	bus.Publish(balancer.Event{Type: balancer.EventBackendAdded})
+	bus.Publish(balancer.Event{Type: balancer.EventBackendDead, Reason: "first"})
+	bus.Publish(balancer.Event{Type: balancer.EventBackendAlive})
+
+	// the events of the other types are filtered, and the full channel drops the events instead of blocking
+	event := <-small.C
+	assert.Equal(t, balancer.EventBackendDead, event.Type)
+	assert.Equal(t, "first", event.Reason)
+	assert.False(t, event.Time.IsZero())
+	assert.Equal(t, uint64(1), small.Dropped())
+
+	bus.Unsubscribe(small)
+	_, ok := <-small.C
+	assert.False(t, ok)
+}
@@ -0,0 +1,86 @@
+package test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bytom/blockcenter/balancer"
+)
+
+func TestConsistentHash(t *testing.T) {
+	backends := balancer.NewBackends()
+	for i := 1; i <= 5; i++ {
+		backends.Add(balancer.NewBackend("localhost:10000/api"+strconv.Itoa(i), 0))
+	}
+	ch := balancer.NewConsistentHash(backends, balancer.DefaultReplicas)
+
+	before := make(map[string][]*balancer.Backend)
+	for i := 0; i < 1000; i++ {
+		key := "bm1q" + strconv.Itoa(i)
+		nodes, err := ch.PickByKey(key, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, 3, len(nodes))
+		assert.NotEqual(t, nodes[0].URL, nodes[1].URL)
+		assert.NotEqual(t, nodes[1].URL, nodes[2].URL)
+		assert.NotEqual(t, nodes[0].URL, nodes[2].URL)
+		before[key] = nodes
+	}
+
+	// only the keys of the deleted node are remapped
+	removed, _ := backends.Get("localhost:10000/api3")
+	backends.Delete(removed)
+	for key, nodes := range before {
+		after, err := ch.PickByKey(key, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nodes[0] != removed {
+			assert.Equal(t, nodes[0].URL, after[0].URL)
+		} else {
+			assert.Equal(t, nodes[1].URL, after[0].URL)
+		}
+	}
+
+	// a dead node is skipped
+	first, _ := backends.Get(0)
+	first.State.SetAlive(false)
+	for key := range before {
+		nodes, err := ch.PickByKey(key, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, 3, len(nodes))
+		for _, node := range nodes {
+			assert.True(t, node.State.Alive())
+		}
+	}
+}
+
+func TestConsistentHashDistribution(t *testing.T) {
+	backends := balancer.NewBackends()
+	for i := 201; i <= 205; i++ {
+		backends.Add(balancer.NewBackend("192.168.1."+strconv.Itoa(i), 0))
+	}
+	ch := balancer.NewConsistentHash(backends, balancer.DefaultReplicas)
+
+	keys := 100000
+	counts := make(map[string]int)
+	for i := 0; i < keys; i++ {
+		nodes, err := ch.PickByKey("bm1q"+strconv.Itoa(i), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[nodes[0].URL]++
+	}
+
+	// every backend gets its fair share of the keys within 25%
+	fair := float64(keys) / float64(backends.Len())
+	assert.Equal(t, backends.Len(), len(counts))
+	for url, n := range counts {
+		assert.InDelta(t, fair, float64(n), fair/4, url)
+	}
+}
@@ -0,0 +1,71 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/bytom/blockcenter/balancer"
+)
+
+// newBalancer builds a balancer without registering it to balancer.Manager,
+// so that the test can run more than once.
+func newBalancer(t *testing.T, opts *balancer.Options) balancer.Balancer {
+	builder := balancer.Get(opts.Type)
+	if builder == nil {
+		t.Fatalf("unknown load balance type: %s", opts.Type)
+	}
+	lb := builder.Build(&http.Client{}, opts)
+	t.Cleanup(lb.Close)
+	return lb
+}
+
+// holdServer starts a server which sends the response header at once and holds
+// the body until release is closed.
+func holdServer(t *testing.T) (server *httptest.Server, release chan struct{}) {
+	release = make(chan struct{})
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	t.Cleanup(func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+		server.Close()
+	})
+	return server, release
+}
+
+// get sends a GET request of the path through the balancer.
+func get(t *testing.T, lb balancer.Balancer, path string) *http.Response {
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	resp, err := lb.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// statusServers starts the servers which respond the statuses, and counts their requests.
+func statusServers(t *testing.T, statuses ...int) ([]string, map[string]int, *sync.Mutex) {
+	var mux sync.Mutex
+	hits := make(map[string]int)
+	urls := make([]string, len(statuses))
+	for i, status := range statuses {
+		status := status
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mux.Lock()
+			hits["http://"+r.Host]++
+			mux.Unlock()
+			w.WriteHeader(status)
+		}))
+		t.Cleanup(server.Close)
+		urls[i] = server.URL
+	}
+	return urls, hits, &mux
+}
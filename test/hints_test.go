@@ -0,0 +1,73 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bytom/blockcenter/balancer"
+	_ "github.com/bytom/blockcenter/balancer/round_robin"
+)
+
+func TestRoutingHints(t *testing.T) {
+	urls := make([]string, 3)
+	for i := range urls {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+		urls[i] = server.URL
+	}
+	lb := newBalancer(t, &balancer.Options{
+		Type: "RoundRobin",
+		Backends: []balancer.BackendOptions{
+			{URL: urls[0], Tags: []string{"archive"}},
+			{URL: urls[1], Tags: []string{"archive", "fast"}},
+			{URL: urls[2]},
+		},
+	})
+
+	// do sends n requests with the context, and returns the backends which receive them
+	do := func(ctx context.Context, n int) map[string]int {
+		counts := make(map[string]int)
+		for i := 0; i < n; i++ {
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+			resp, err := lb.Do(req)
+			if !assert.NoError(t, err) {
+				continue
+			}
+			resp.Body.Close()
+			counts["http://"+resp.Request.URL.Host]++
+		}
+		return counts
+	}
+
+	ctx := context.Background()
+	assert.Equal(t, map[string]int{urls[2]: 6}, do(balancer.WithPreferredBackend(ctx, urls[2]), 6))
+
+	// the preferred backend falls back to the picker when it is unavailable
+	preferred, _ := lb.Backends().Get(urls[2])
+	preferred.State.SetAlive(false)
+	fallback := do(balancer.WithPreferredBackend(ctx, urls[2]), 6)
+	assert.Equal(t, 0, fallback[urls[2]])
+	assert.Equal(t, 6, fallback[urls[0]]+fallback[urls[1]])
+	preferred.State.SetAlive(true)
+
+	excluded := do(balancer.WithExcludedBackends(ctx, urls[0], urls[2]), 6)
+	assert.Equal(t, map[string]int{urls[1]: 6}, excluded)
+
+	tagged := do(balancer.WithRequiredTags(ctx, "archive"), 6)
+	assert.Equal(t, 0, tagged[urls[2]])
+	assert.Equal(t, 6, tagged[urls[0]]+tagged[urls[1]])
+	assert.Equal(t, map[string]int{urls[1]: 6}, do(balancer.WithRequiredTags(ctx, "archive", "fast"), 6))
+
+	// the same hash key always goes to the same backend
+	backends, err := lb.PickByKey("address", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{backends[0].URL: 6}, do(balancer.WithHashKey(ctx, "address"), 6))
+
+	req, _ := http.NewRequestWithContext(balancer.WithRequiredTags(ctx, "missing"), http.MethodGet, "/", nil)
+	_, err = lb.Do(req)
+	assert.Equal(t, balancer.ErrNoBackendAvailable, err)
+}
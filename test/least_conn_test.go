@@ -0,0 +1,63 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bytom/blockcenter/balancer"
+	_ "github.com/bytom/blockcenter/balancer/least_conn"
+)
+
+func TestLeastConn(t *testing.T) {
+	a, _ := holdServer(t)
+	b, _ := holdServer(t)
+	lb := newBalancer(t, &balancer.Options{Type: "LeastConn", Urls: []string{a.URL, b.URL}})
+
+	// the response is in flight until its body is closed
+	first := get(t, lb, "/")
+	busy, _ := lb.Backends().Get("http://" + first.Request.URL.Host)
+	assert.Equal(t, int64(1), busy.InFlight())
+
+	for i := 0; i < 4; i++ {
+		backend, err := lb.Pick()
+		assert.NoError(t, err)
+		assert.NotEqual(t, busy.URL, backend.URL)
+	}
+
+	second := get(t, lb, "/")
+	idle, _ := lb.Backends().Get("http://" + second.Request.URL.Host)
+	assert.NotEqual(t, busy.URL, idle.URL)
+	assert.Equal(t, int64(1), idle.InFlight())
+
+	first.Body.Close()
+	first.Body.Close()
+	assert.Equal(t, int64(0), busy.InFlight())
+	backend, err := lb.Pick()
+	assert.NoError(t, err)
+	assert.Equal(t, busy.URL, backend.URL)
+
+	second.Body.Close()
+	assert.Equal(t, int64(0), idle.InFlight())
+}
+
+func TestInFlightError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	lb := newBalancer(t, &balancer.Options{
+		Type:  "LeastConn",
+		Urls:  []string{url},
+		Retry: balancer.RetryOptions{MaxAttempts: 1},
+	})
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	_, err := lb.Do(req)
+	assert.Error(t, err)
+
+	backend, _ := lb.Backends().Get(url)
+	assert.Equal(t, int64(0), backend.InFlight())
+	assert.Equal(t, uint64(1), backend.Statistic.Failure())
+}
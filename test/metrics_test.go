@@ -0,0 +1,97 @@
+package test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bytom/blockcenter/balancer"
+	_ "github.com/bytom/blockcenter/balancer/round_robin"
+)
+
+func TestMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	name := fmt.Sprintf("test-metrics-%d", time.Now().UnixNano())
+	opts := balancer.Options{
+		Name:      name,
+		Type:      "RoundRobin",
+		Urls:      []string{server.URL},
+		Statistic: balancer.StatisticOptions{Enable: true, Port: 30001},
+	}
+	lb, err := balancer.Manager.Balancer(&opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range []struct{ method, path string }{{"GET", "/ok"}, {"GET", "/ok"}, {"GET", "/ok"}, {"POST", "/fail"}} {
+		req, _ := http.NewRequest(r.method, r.path, nil)
+		resp, err := lb.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	var body []byte
+	for i := 0; i < 10 && body == nil; i++ {
+		time.Sleep(100 * time.Millisecond)
+		if resp, err := http.Get("http://localhost:30001/metrics"); err == nil {
+			body, _ = ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+	}
+
+	labels := `balancer="` + name + `",backend="` + server.URL + `"`
+	for _, line := range []string{
+		"# TYPE balancer_request_duration_seconds histogram",
+		"balancer_backend_up{" + labels + "} 1",
+		"balancer_requests_total{" + labels + `,class="2xx"} 3`,
+		"balancer_requests_total{" + labels + `,class="5xx"} 1`,
+		"balancer_request_duration_seconds_bucket{" + labels + `,le="+Inf"} 4`,
+		"balancer_request_duration_seconds_count{" + labels + "} 4",
+		"balancer_retries_total{" + labels + "} 0",
+		"balancer_in_flight_requests{" + labels + "} 0",
+	} {
+		assert.Contains(t, string(body), line+"\n")
+	}
+}
+
+func TestStatistic(t *testing.T) {
+	statistic := &balancer.Statistic{}
+	for i := 1; i <= 100; i++ {
+		statistic.IncStatus(http.StatusOK)
+		statistic.ObserveLatency(time.Duration(i) * time.Millisecond)
+	}
+	statistic.IncStatus(0)
+	statistic.IncStatus(http.StatusBadGateway)
+	statistic.AddBytesSent(10)
+	statistic.AddBytesReceived(20)
+
+	assert.Equal(t, []uint64{1, 0, 100, 0, 0, 1}, statistic.Statuses())
+	assert.Equal(t, uint64(10), statistic.BytesSent())
+	assert.Equal(t, uint64(20), statistic.BytesReceived())
+
+	histogram := statistic.Histogram()
+	assert.Equal(t, uint64(100), histogram.Count())
+	assert.InDelta(t, 50*time.Millisecond, histogram.Quantile(0.5), float64(time.Millisecond))
+	assert.InDelta(t, 95*time.Millisecond, histogram.Quantile(0.95), float64(time.Millisecond))
+	assert.Equal(t, time.Duration(0), (&balancer.Histogram{}).Quantile(0.99))
+
+	for _, minutes := range []int{1, 5, 15} {
+		window := statistic.Window(minutes)
+		assert.Equal(t, uint64(102), window.Requests)
+		assert.Equal(t, uint64(2), window.Failures)
+		assert.Equal(t, histogram.Quantile(0.99), window.P99)
+	}
+}
@@ -0,0 +1,95 @@
+package test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bytom/blockcenter/balancer"
+	"github.com/bytom/blockcenter/balancer/health"
+)
+
+func TestOutlierDetector(t *testing.T) {
+	backends := balancer.NewBackends()
+	for i := 1; i <= 10; i++ {
+		backends.Add(balancer.NewBackend("localhost:10000/api"+strconv.Itoa(i), 0))
+	}
+	detector := health.NewOutlierDetector(&balancer.OutlierOptions{MaxEjectionPercent: 20}, backends)
+
+	// api1 and api2 hit consecutive errors, api3 has a low success rate
+	backends.Range(func(index int, backend *balancer.Backend) bool {
+		for i := 0; i < 200; i++ {
+			if index == 2 && i%2 == 0 {
+				backend.Statistic.IncFailure()
+			} else {
+				backend.Statistic.IncSuccess()
+			}
+		}
+		if index < 2 {
+			for i := 0; i < 5; i++ {
+				backend.Statistic.IncConsecutiveErrors()
+			}
+		}
+		return true
+	})
+
+	detector.Detect()
+
+	ejected := make([]string, 0)
+	backends.Range(func(index int, backend *balancer.Backend) bool {
+		if backend.State.Ejected() {
+			ejected = append(ejected, backend.URL)
+			assert.False(t, backend.Available())
+		}
+		return true
+	})
+	// at most 20% of the backends are ejected
+	assert.Equal(t, []string{"localhost:10000/api1", "localhost:10000/api2"}, ejected)
+
+	// api3 is ejected after api1 is readmitted
+	api1, _ := backends.Get(0)
+	api1.State.Readmit()
+	backends.Range(func(index int, backend *balancer.Backend) bool {
+		for i := 0; i < 200; i++ {
+			if index == 2 && i%2 == 0 {
+				backend.Statistic.IncFailure()
+			} else {
+				backend.Statistic.IncSuccess()
+			}
+		}
+		return true
+	})
+	detector.Detect()
+
+	api3, _ := backends.Get(2)
+	assert.True(t, api3.State.Ejected())
+	assert.False(t, api1.State.Ejected())
+}
+
+func TestOutlierDetectorWorstFirst(t *testing.T) {
+	backends := balancer.NewBackends()
+	for i := 1; i <= 20; i++ {
+		backends.Add(balancer.NewBackend("localhost:10000/api"+strconv.Itoa(i), 0))
+	}
+	detector := health.NewOutlierDetector(&balancer.OutlierOptions{MaxEjectionPercent: 5}, backends)
+
+	// api19 and api20 are both outliers, only one of them can be ejected
+	backends.Range(func(index int, backend *balancer.Backend) bool {
+		for i := 0; i < 100; i++ {
+			if (index == 18 && i < 40) || (index == 19 && i < 50) {
+				backend.Statistic.IncFailure()
+			} else {
+				backend.Statistic.IncSuccess()
+			}
+		}
+		return true
+	})
+
+	detector.Detect()
+
+	api19, _ := backends.Get(18)
+	api20, _ := backends.Get(19)
+	assert.False(t, api19.State.Ejected())
+	assert.True(t, api20.State.Ejected())
+}
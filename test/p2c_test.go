@@ -0,0 +1,51 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bytom/blockcenter/balancer"
+	_ "github.com/bytom/blockcenter/balancer/p2c"
+)
+
+func TestP2C(t *testing.T) {
+	lb := newBalancer(t, &balancer.Options{
+		Type: "P2C",
+		Urls: []string{"localhost:10000/api1", "localhost:10000/api2", "localhost:10000/api3"},
+	})
+	api1, _ := lb.Backends().Get("localhost:10000/api1")
+	api2, _ := lb.Backends().Get("localhost:10000/api2")
+	api3, _ := lb.Backends().Get("localhost:10000/api3")
+	counts := func() map[string]int {
+		counts := make(map[string]int)
+		for i := 0; i < 100; i++ {
+			backend, err := lb.Pick()
+			assert.NoError(t, err)
+			counts[backend.URL]++
+		}
+		return counts
+	}
+
+	// the most loaded backend always loses the comparison
+	api1.IncInFlight()
+	api1.IncInFlight()
+	counts1 := counts()
+	assert.Equal(t, 0, counts1[api1.URL])
+	assert.True(t, counts1[api2.URL] > 0 && counts1[api3.URL] > 0, "%v", counts1)
+
+	// only the available backends are picked, the two left are always compared
+	api3.State.SetAlive(false)
+	assert.Equal(t, map[string]int{api2.URL: 100}, counts())
+
+	// the failure ratio breaks the tie of the requests in flight
+	api2.IncInFlight()
+	api2.IncInFlight()
+	api2.Statistic.IncFailure()
+	assert.Equal(t, map[string]int{api1.URL: 100}, counts())
+
+	api1.State.SetAlive(false)
+	api2.State.SetAlive(false)
+	_, err := lb.Pick()
+	assert.Equal(t, balancer.ErrNoBackendAvailable, err)
+}
@@ -0,0 +1,69 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bytom/blockcenter/balancer"
+	_ "github.com/bytom/blockcenter/balancer/peak_ewma"
+)
+
+func TestPeakEWMA(t *testing.T) {
+	lb := newBalancer(t, &balancer.Options{
+		Type: "PeakEWMA",
+		Urls: []string{"localhost:10000/api1", "localhost:10000/api2", "localhost:10000/api3"},
+	})
+	backend := func(url string) *balancer.Backend {
+		b, _ := lb.Backends().Get(url)
+		return b
+	}
+	pick := func() string {
+		b, err := lb.Pick()
+		assert.NoError(t, err)
+		return b.URL
+	}
+
+	// cold start: each unsampled backend is probed by one request
+	unsampled := backend("localhost:10000/api3")
+	backend("localhost:10000/api1").Statistic.ObserveLatency(50 * time.Millisecond)
+	backend("localhost:10000/api2").Statistic.ObserveLatency(10 * time.Millisecond)
+	assert.Equal(t, unsampled.URL, pick())
+	unsampled.IncInFlight()
+	assert.Equal(t, "localhost:10000/api2", pick())
+
+	// the lowest latency, weighted by the requests in flight
+	unsampled.Statistic.ObserveLatency(100 * time.Millisecond)
+	unsampled.DecInFlight()
+	assert.Equal(t, "localhost:10000/api2", pick())
+	for i := 0; i < 5; i++ {
+		backend("localhost:10000/api2").IncInFlight()
+	}
+	assert.Equal(t, "localhost:10000/api1", pick())
+}
+
+func TestPeakEWMADecay(t *testing.T) {
+	lb := newBalancer(t, &balancer.Options{
+		Type: "PeakEWMA",
+		Urls: []string{"localhost:10000/api1", "localhost:10000/api2"},
+	})
+	old, _ := lb.Backends().Get("localhost:10000/api1")
+	fresh, _ := lb.Backends().Get("localhost:10000/api2")
+
+	// the peak is kept at once, and decays over time
+	old.Statistic.ObserveLatency(10 * time.Millisecond)
+	old.Statistic.ObserveLatency(100 * time.Millisecond)
+	latency, ok := old.Statistic.Latency()
+	assert.True(t, ok)
+	assert.InDelta(t, 100*time.Millisecond, latency, float64(time.Millisecond))
+
+	time.Sleep(300 * time.Millisecond)
+	fresh.Statistic.ObserveLatency(99 * time.Millisecond)
+	decayed, _ := old.Statistic.Latency()
+	assert.True(t, decayed < 99*time.Millisecond, "%s", decayed)
+
+	backend, err := lb.Pick()
+	assert.NoError(t, err)
+	assert.Equal(t, old.URL, backend.URL)
+}
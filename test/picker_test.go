@@ -0,0 +1,142 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bytom/blockcenter/balancer"
+	"github.com/bytom/blockcenter/balancer/base"
+)
+
+func init() {
+	balancer.Register(base.NewBalancerBuilder("TestPickOnly", &pickOnlyBuilder{}))
+	balancer.Register(base.NewBalancerBuilder("TestRecord", records))
+}
+
+type pickOnlyBuilder struct{}
+
+func (*pickOnlyBuilder) Build(backends *balancer.Backends) balancer.Picker {
+	return &pickOnlyPicker{backends: backends}
+}
+
+// pickOnlyPicker is a round-robin picker which only implements Pick.
+type pickOnlyPicker struct {
+	backends *balancer.Backends
+	next     int
+	mux      sync.Mutex
+}
+
+func (p *pickOnlyPicker) Pick() (*balancer.Backend, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	backend, ok := p.backends.Get(p.next % p.backends.Len())
+	if !ok {
+		return nil, balancer.ErrNoBackendAvailable
+	}
+	p.next++
+	return backend, nil
+}
+
+// records keeps the last picker it builds.
+var records = &recordBuilder{}
+
+type recordBuilder struct {
+	last *recordPicker
+}
+
+func (b *recordBuilder) Build(backends *balancer.Backends) balancer.Picker {
+	b.last = &recordPicker{backends: backends}
+	return b.last
+}
+
+// recordPicker records the pick information, and picks the first allowed backend.
+type recordPicker struct {
+	backends *balancer.Backends
+	infos    []balancer.PickInfo
+	mux      sync.Mutex
+}
+
+func (p *recordPicker) Pick() (*balancer.Backend, error) {
+	return p.PickWithInfo(&balancer.PickInfo{})
+}
+
+func (p *recordPicker) PickWithInfo(info *balancer.PickInfo) (*balancer.Backend, error) {
+	p.mux.Lock()
+	p.infos = append(p.infos, *info)
+	p.mux.Unlock()
+
+	var picked *balancer.Backend
+	p.backends.Range(func(index int, backend *balancer.Backend) bool {
+		if info.Allow(backend) {
+			picked = backend
+			return false
+		}
+		return true
+	})
+	if picked == nil {
+		return nil, balancer.ErrNoBackendAvailable
+	}
+	return picked, nil
+}
+
+func TestPickerAdapter(t *testing.T) {
+	urls, hits, mux := statusServers(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK)
+	lb := newBalancer(t, &balancer.Options{
+		Type:  "TestPickOnly",
+		Urls:  urls,
+		Retry: balancer.RetryOptions{MaxAttempts: 3, Backoff: 1},
+	})
+
+	// the adapter skips the tried backends, so each attempt goes to another backend
+	resp := get(t, lb, "/")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	mux.Lock()
+	assert.Equal(t, map[string]int{urls[0]: 1, urls[1]: 1, urls[2]: 1}, hits)
+	mux.Unlock()
+
+	// and the backends excluded by the hints
+	req, _ := http.NewRequestWithContext(balancer.WithExcludedBackends(context.Background(), urls[0], urls[1]), http.MethodGet, "/", nil)
+	for i := 0; i < 3; i++ {
+		resp, err := lb.Do(req)
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		}
+	}
+	mux.Lock()
+	assert.Equal(t, 4, hits[urls[2]])
+	mux.Unlock()
+}
+
+func TestPickInfo(t *testing.T) {
+	urls, _, _ := statusServers(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	lb := newBalancer(t, &balancer.Options{
+		Type:  "TestRecord",
+		Urls:  urls,
+		Retry: balancer.RetryOptions{MaxAttempts: 3, Backoff: 1},
+	})
+
+	resp := get(t, lb, "/path")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	picker := records.last
+	picker.mux.Lock()
+	defer picker.mux.Unlock()
+	assert.Equal(t, 3, len(picker.infos))
+	for i, info := range picker.infos {
+		assert.Equal(t, i, info.Attempt)
+		assert.Equal(t, i, len(info.Tried))
+		assert.Equal(t, "/path", info.Request.URL.Path)
+		assert.NotNil(t, info.Ctx)
+		for j, tried := range info.Tried {
+			assert.Equal(t, urls[j], tried.URL)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bytom/blockcenter/balancer"
+	"github.com/bytom/blockcenter/balancer/health"
+)
+
+func TestProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/net-info":
+			_, _ = w.Write([]byte(`{"status":"success","data":{"peers":[{"id":"a"}]}}`))
+		case "/fail":
+			_, _ = w.Write([]byte(`{"status":"fail"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	backend := balancer.NewBackend(server.URL, 0)
+
+	cases := []struct {
+		opts balancer.ProbeOptions
+		ok   bool
+	}{
+		{balancer.ProbeOptions{}, true},
+		{balancer.ProbeOptions{Path: "/fail", JSONPath: "status", JSONValue: "success"}, false},
+		{balancer.ProbeOptions{Path: "/fail", JSONPath: "status"}, false},
+		{balancer.ProbeOptions{Path: "/net-info", JSONPath: "status"}, true},
+		{balancer.ProbeOptions{Path: "/missing"}, false},
+		{balancer.ProbeOptions{Path: "/missing", Status: http.StatusNotFound}, true},
+		{balancer.ProbeOptions{Path: "/net-info", JSONPath: "data.peers.0.id", JSONValue: "a"}, true},
+		{balancer.ProbeOptions{Path: "/net-info", Body: `"peers"`}, true},
+		{balancer.ProbeOptions{Path: "/fail", Body: `"peers"`}, false},
+		{balancer.ProbeOptions{Type: health.ProbeTCP}, true},
+	}
+	for i, c := range cases {
+		ping, err := health.NewPingHandler(&balancer.DoctorOptions{Probe: c.opts})
+		assert.NoError(t, err)
+		assert.Equal(t, c.ok, ping(backend) == nil, "case %d", i)
+	}
+
+	_, err := health.NewPingHandler(&balancer.DoctorOptions{Probe: balancer.ProbeOptions{Type: "udp"}})
+	assert.Error(t, err)
+}
+
+func TestChainProbe(t *testing.T) {
+	near := netInfoServer(false, block(995), 1000, "mainnet")
+	defer near.Close()
+	lagging := netInfoServer(false, block(900), 1000, "mainnet")
+	defer lagging.Close()
+
+	// a node within the default max lag of 10 blocks is healthy
+	for _, c := range []struct {
+		opts balancer.ChainOptions
+		url  string
+		ok   bool
+	}{
+		{balancer.ChainOptions{}, near.URL, true},
+		{balancer.ChainOptions{}, lagging.URL, false},
+		{balancer.ChainOptions{MaxLag: 1}, near.URL, false},
+		{balancer.ChainOptions{MaxLag: 100}, lagging.URL, true},
+	} {
+		ping, err := health.NewPingHandler(&balancer.DoctorOptions{Probe: balancer.ProbeOptions{Type: health.ProbeChain}, Chain: c.opts})
+		assert.NoError(t, err)
+		assert.Equal(t, c.ok, ping(balancer.NewBackend(c.url, 0)) == nil, "%+v", c.opts)
+	}
+}
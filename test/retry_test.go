@@ -0,0 +1,236 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bytom/blockcenter/balancer"
+	_ "github.com/bytom/blockcenter/balancer/round_robin"
+)
+
+func TestRetryReplayBody(t *testing.T) {
+	fail := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer fail.Close()
+
+	echo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+	defer echo.Close()
+
+	lb := newBalancer(t, &balancer.Options{
+		Name: "test-retry",
+		Type: "RoundRobin",
+		Urls: []string{fail.URL, echo.URL},
+		Retry: balancer.RetryOptions{
+			Backoff:     1,
+			Paths:       []string{"/submit"},
+			MaxBodySize: 16,
+		},
+	})
+
+	do := func(payload string) (*http.Response, error) {
+		// a body without GetBody is buffered by the balancer
+		req, err := http.NewRequest("POST", "/submit", ioutil.NopCloser(strings.NewReader(payload)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(balancer.WithPreferredBackend(context.Background(), fail.URL))
+		return lb.Do(req)
+	}
+
+	resp, err := do(`{"tx":"0001"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `{"tx":"0001"}`, string(body))
+
+	_, err = do(`{"tx":"0001","more":"data"}`)
+	assert.True(t, errors.Is(err, balancer.ErrBodyNotReplayable))
+}
+
+// send sends a request of the method and path through the balancer, the body is closed at once.
+func send(t *testing.T, lb balancer.Balancer, method, path string) int {
+	req, _ := http.NewRequest(method, path, nil)
+	resp, err := lb.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+// total returns the requests of all the servers, and resets the counters.
+func total(hits map[string]int, mux *sync.Mutex) int {
+	mux.Lock()
+	defer mux.Unlock()
+	n := 0
+	for url, hit := range hits {
+		n += hit
+		delete(hits, url)
+	}
+	return n
+}
+
+func TestRetryIdempotent(t *testing.T) {
+	unavailable := http.StatusServiceUnavailable
+	urls, hits, mux := statusServers(t, unavailable, unavailable, unavailable, unavailable, unavailable)
+	lb := newBalancer(t, &balancer.Options{
+		Type:  "RoundRobin",
+		Urls:  urls,
+		Retry: balancer.RetryOptions{Backoff: 1, Paths: []string{"/submit"}},
+	})
+
+	// a non-idempotent request is not retried
+	assert.Equal(t, unavailable, send(t, lb, http.MethodPost, "/other"))
+	assert.Equal(t, 1, total(hits, mux))
+
+	// the listed path is retried although the method is not idempotent
+	assert.Equal(t, unavailable, send(t, lb, http.MethodPost, "/submit"))
+	assert.Equal(t, 4, total(hits, mux))
+
+	// an idempotent request is retried up to the max attempts, each attempt goes to another backend
+	assert.Equal(t, unavailable, send(t, lb, http.MethodGet, "/"))
+	mux.Lock()
+	assert.Equal(t, 4, len(hits))
+	for url, n := range hits {
+		assert.Equal(t, 1, n, url)
+	}
+	mux.Unlock()
+	total(hits, mux)
+
+	capped := newBalancer(t, &balancer.Options{
+		Type:  "RoundRobin",
+		Urls:  urls,
+		Retry: balancer.RetryOptions{Backoff: 1, MaxAttempts: 2},
+	})
+	assert.Equal(t, unavailable, send(t, capped, http.MethodGet, "/"))
+	assert.Equal(t, 2, total(hits, mux))
+}
+
+func TestRetryFilter(t *testing.T) {
+	urls, hits, mux := statusServers(t, http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK)
+	lb := newBalancer(t, &balancer.Options{
+		Type:  "RoundRobin",
+		Urls:  urls,
+		Retry: balancer.RetryOptions{Backoff: 1, Statuses: []int{http.StatusBadGateway}},
+	})
+	prefer := func(url string) int {
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		resp, err := lb.Do(req.WithContext(balancer.WithPreferredBackend(context.Background(), url)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// only the listed statuses are retried
+	assert.Equal(t, http.StatusServiceUnavailable, prefer(urls[0]))
+	assert.Equal(t, 1, total(hits, mux))
+	assert.Equal(t, http.StatusOK, prefer(urls[1]))
+	assert.Equal(t, 2, total(hits, mux))
+
+	// only the errors containing the listed messages are retried
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+	for _, c := range []struct {
+		errors  []string
+		retried bool
+	}{
+		{[]string{"connection refused"}, true},
+		{[]string{"timeout"}, false},
+	} {
+		lb := newBalancer(t, &balancer.Options{
+			Type:  "RoundRobin",
+			Urls:  []string{closed.URL, urls[2]},
+			Retry: balancer.RetryOptions{Backoff: 1, Errors: c.errors},
+		})
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		resp, err := lb.Do(req.WithContext(balancer.WithPreferredBackend(context.Background(), closed.URL)))
+		if c.retried {
+			assert.NoError(t, err, c.errors)
+			resp.Body.Close()
+			assert.Equal(t, 1, total(hits, mux))
+		} else {
+			assert.Error(t, err, c.errors)
+			assert.Equal(t, 0, total(hits, mux))
+		}
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	unavailable := http.StatusServiceUnavailable
+	urls, hits, mux := statusServers(t, unavailable, unavailable)
+	lb := newBalancer(t, &balancer.Options{
+		Type:  "RoundRobin",
+		Urls:  urls,
+		Retry: balancer.RetryOptions{Backoff: 1, MaxAttempts: 2, Budget: 0.1, MinRetries: 1},
+	})
+
+	// the retries in 10 seconds are limited to 10% of the requests plus 1 retry per second,
+	// so the first 12 of 20 requests are retried and the budget is exhausted then
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, unavailable, send(t, lb, http.MethodGet, "/"))
+		if i < 12 {
+			assert.Equal(t, 2, total(hits, mux), i)
+		} else {
+			assert.Equal(t, 1, total(hits, mux), i)
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	var mux sync.Mutex
+	times := make([]time.Time, 0)
+	urls := make([]string, 4)
+	for i := range urls {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mux.Lock()
+			times = append(times, time.Now())
+			mux.Unlock()
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+		urls[i] = server.URL
+	}
+	lb := newBalancer(t, &balancer.Options{
+		Type:  "RoundRobin",
+		Urls:  urls,
+		Retry: balancer.RetryOptions{Backoff: 40, MaxBackoff: 100, Jitter: 0.5},
+	})
+
+	send(t, lb, http.MethodGet, "/")
+
+	// the backoff doubles after each attempt up to the max backoff, and the jitter takes up to half of it
+	mux.Lock()
+	defer mux.Unlock()
+	if !assert.Equal(t, 4, len(times)) {
+		return
+	}
+	for i, backoff := range []time.Duration{40, 80, 100} {
+		backoff *= time.Millisecond
+		gap := times[i+1].Sub(times[i])
+		assert.True(t, gap >= backoff/2, "attempt %d waits %s", i+1, gap)
+		assert.True(t, gap < backoff+50*time.Millisecond, "attempt %d waits %s", i+1, gap)
+	}
+}
@@ -0,0 +1,71 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bytom/blockcenter/balancer"
+	_ "github.com/bytom/blockcenter/balancer/round_robin"
+)
+
+type ctxKey struct{}
+
+func TestRewriteRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ := r.BasicAuth()
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"path":         r.URL.Path,
+			"query":        r.URL.RawQuery,
+			"content-type": r.Header.Get("Content-Type"),
+			"x-node":       r.Header.Get("X-Node"),
+			"username":     username,
+			"password":     password,
+		})
+	}))
+	defer server.Close()
+
+	lb := newBalancer(t, &balancer.Options{
+		Type: "RoundRobin",
+		Backends: []balancer.BackendOptions{
+			{
+				URL:      server.URL + "/api1?token=abc",
+				Headers:  map[string]string{"X-Node": "node-1"},
+				Username: "user",
+				Password: "pass",
+			},
+		},
+	})
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	req, err := http.NewRequestWithContext(ctx, "GET", "/net-info?height=10", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := lb.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, "value", resp.Request.Context().Value(ctxKey{}))
+
+	result := make(map[string]string)
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]string{
+		"path":         "/api1/net-info",
+		"query":        "token=abc&height=10",
+		"content-type": "application/json",
+		"x-node":       "node-1",
+		"username":     "user",
+		"password":     "pass",
+	}, result)
+}
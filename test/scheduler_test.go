@@ -0,0 +1,50 @@
+package test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bytom/blockcenter/balancer"
+	"github.com/bytom/blockcenter/balancer/task"
+)
+
+func TestScheduler(t *testing.T) {
+	var first, second, removed int64
+	s1, s2 := task.NewScheduler(), task.NewScheduler()
+	assert.NoError(t, s1.Add("job", task.NewJob("* * * * * *", func() { atomic.AddInt64(&first, 1) })))
+	assert.NoError(t, s2.Add("job", task.NewJob("@every 1s", func() { atomic.AddInt64(&second, 1) })))
+	assert.NoError(t, s2.Add("removed", task.NewJob("* * * * * ?", func() { atomic.AddInt64(&removed, 1) })))
+	assert.Error(t, s2.Add("invalid", task.NewJob("invalid", func() {})))
+	s1.Start()
+	s2.Start()
+	defer s2.Stop()
+
+	s2.Remove("removed")
+	assert.Equal(t, 1, s2.Len())
+	s1.Stop()
+
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, int64(0), atomic.LoadInt64(&first))
+	assert.Equal(t, int64(0), atomic.LoadInt64(&removed))
+	assert.True(t, atomic.LoadInt64(&second) > 0)
+}
+
+func TestWatchBackends(t *testing.T) {
+	backends := balancer.NewBackends()
+	var first, second int64
+	unwatch := backends.Watch(func(backend *balancer.Backend, added bool) { atomic.AddInt64(&first, 1) })
+	backends.Watch(func(backend *balancer.Backend, added bool) { atomic.AddInt64(&second, 1) })
+
+	backend := balancer.NewBackend("localhost:10000/api1", 0)
+	backends.Add(backend)
+	unwatch()
+	unwatch()
+	backends.Delete(backend)
+
+	// the removed watcher is not called, the other watchers are still called
+	assert.Equal(t, int64(1), atomic.LoadInt64(&first))
+	assert.Equal(t, int64(2), atomic.LoadInt64(&second))
+}
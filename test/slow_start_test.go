@@ -0,0 +1,40 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bytom/blockcenter/balancer"
+	_ "github.com/bytom/blockcenter/balancer/round_robin"
+)
+
+func TestSlowStart(t *testing.T) {
+	lb := newBalancer(t, &balancer.Options{
+		Name:      "test-slow-start",
+		Type:      "RoundRobin",
+		Urls:      []string{"localhost:10000/api1", "localhost:10000/api2"},
+		SlowStart: balancer.SlowStartOptions{Enable: true, Window: 600, MinPercent: 10},
+	})
+
+	warming, _ := lb.Backends().Get("localhost:10000/api2")
+	assert.Equal(t, 1.0, warming.WarmupFactor())
+	warming.State.SetAlive(false)
+	warming.State.SetAlive(true)
+	assert.InDelta(t, 0.1, warming.WarmupFactor(), 0.01)
+
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		backend, err := lb.Pick()
+		assert.NoError(t, err)
+		counts[backend.URL]++
+	}
+	assert.True(t, counts[warming.URL] > 0 && counts[warming.URL] < 150, "%v", counts)
+
+	// the warming up backend is picked if it is the only one
+	first, _ := lb.Backends().Get("localhost:10000/api1")
+	first.State.SetAlive(false)
+	backend, err := lb.Pick()
+	assert.NoError(t, err)
+	assert.Equal(t, warming.URL, backend.URL)
+}
@@ -0,0 +1,69 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bytom/blockcenter/balancer"
+	_ "github.com/bytom/blockcenter/balancer/weighted_round_robin"
+)
+
+func TestWeightedRoundRobin(t *testing.T) {
+	opts := balancer.Options{
+		Name: fmt.Sprintf("test-wrr-%d", time.Now().UnixNano()),
+		Type: "WeightedRoundRobin",
+		Urls: []string{"localhost:10000/api1"},
+		Backends: []balancer.BackendOptions{
+			{URL: "localhost:10000/api2", Weight: 2},
+			{URL: "localhost:10000/api3", Weight: 3},
+		},
+	}
+
+	lb, err := balancer.Manager.Balancer(&opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pick := func(n int) []string {
+		urls := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			backend, err := lb.Pick()
+			if err != nil {
+				t.Fatal(err)
+			}
+			urls = append(urls, backend.URL)
+		}
+		return urls
+	}
+
+	// smooth weighted round-robin: {a, b, c} with weights {1, 2, 3} gives c b a c b c
+	assert.Equal(t, []string{
+		"localhost:10000/api3",
+		"localhost:10000/api2",
+		"localhost:10000/api1",
+		"localhost:10000/api3",
+		"localhost:10000/api2",
+		"localhost:10000/api3",
+	}, pick(6))
+
+	// reload the same nodes with a new weight, the rotation continues
+	opts.Backends[0].Weight = 4
+	if err := balancer.Manager.UpdateOptions([]*balancer.Options{&opts}); err != nil {
+		t.Fatal(err)
+	}
+
+	backend, ok := lb.Backends().Get("localhost:10000/api2")
+	assert.True(t, ok)
+	assert.Equal(t, 4, backend.Weight())
+
+	counts := make(map[string]int)
+	for _, url := range pick(80) {
+		counts[url]++
+	}
+	assert.Equal(t, 10, counts["localhost:10000/api1"])
+	assert.Equal(t, 40, counts["localhost:10000/api2"])
+	assert.Equal(t, 30, counts["localhost:10000/api3"])
+}
@@ -0,0 +1,73 @@
+package test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bytom/blockcenter/balancer"
+)
+
+func TestSlidingWindow(t *testing.T) {
+	window := balancer.NewSlidingWindow(400*time.Millisecond, 10)
+	window.Add(false)
+	window.Add(true)
+	time.Sleep(200 * time.Millisecond)
+	window.Add(false)
+
+	success, failure := window.Counts()
+	assert.Equal(t, uint64(1), success)
+	assert.Equal(t, uint64(2), failure)
+
+	// the buckets out of the window expire
+	time.Sleep(250 * time.Millisecond)
+	success, failure = window.Counts()
+	assert.Equal(t, uint64(0), success)
+	assert.Equal(t, uint64(1), failure)
+
+	time.Sleep(200 * time.Millisecond)
+	success, failure = window.Counts()
+	assert.Equal(t, uint64(0), success)
+	assert.Equal(t, uint64(0), failure)
+}
+
+func TestFailurePolicy(t *testing.T) {
+	// count mode trips at the max failures
+	count := balancer.FailurePolicy{MaxFailures: 3}
+	assert.False(t, count.Unhealthy(0, 2))
+	assert.True(t, count.Unhealthy(1000, 3))
+
+	// ratio mode trips at the ratio once there are enough requests
+	ratio := balancer.FailurePolicy{MaxFailures: 3, FailureRatio: 0.5, MinRequests: 10}
+	assert.False(t, ratio.Unhealthy(0, 9))
+	assert.False(t, ratio.Unhealthy(6, 4))
+	assert.True(t, ratio.Unhealthy(5, 5))
+	assert.True(t, ratio.Unhealthy(10, 90))
+
+	state := balancer.NewState(time.Minute, 0)
+	for i := 0; i < 9; i++ {
+		state.AddFail(errors.New("failure"))
+	}
+	assert.True(t, state.HealthCheck(ratio))
+	state.AddSuccess()
+	assert.False(t, state.HealthCheck(ratio))
+	assert.False(t, state.Alive())
+}
+
+func TestFailureRing(t *testing.T) {
+	state := balancer.NewState(time.Minute, 3)
+	for i := 1; i <= 5; i++ {
+		state.AddFail(errors.New(strconv.Itoa(i)))
+	}
+
+	// the ring keeps the last 3 failures, the oldest first
+	messages := make([]string, 0)
+	for _, failure := range state.Failures() {
+		messages = append(messages, failure.Error())
+	}
+	assert.Equal(t, []string{"3", "4", "5"}, messages)
+	assert.Equal(t, 5, state.LenFail())
+}
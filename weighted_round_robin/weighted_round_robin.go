@@ -0,0 +1,79 @@
+package weighted_round_robin
+
+import (
+	"sync"
+
+	"github.com/bytom/blockcenter/balancer"
+	"github.com/bytom/blockcenter/balancer/base"
+)
+
+// Name is the name of WeightedRoundRobin Builder.
+const Name = "WeightedRoundRobin"
+
+func init() {
+	balancer.Register(newBuilder())
+}
+
+// newBuilder creates a new weighted roundrobin balancer builder.
+func newBuilder() balancer.Builder {
+	return base.NewBalancerBuilder(Name, &wrrPickerBuilder{})
+}
+
+type wrrPickerBuilder struct{}
+
+func (*wrrPickerBuilder) Build(backends *balancer.Backends) balancer.Picker {
+	return &wrrPicker{
+		backends: backends,
+		current:  make(map[string]int),
+	}
+}
+
+// wrrPicker is a smooth weighted round-robin picker, the same as nginx.
+// The current weight is kept by url, so the rotation of the unchanged
+// backends is not reset when the backends are updated.
+type wrrPicker struct {
+	backends *balancer.Backends
+	current  map[string]int
+	mux      sync.Mutex
+}
+
+func (p *wrrPicker) Pick() (*balancer.Backend, error) {
+	return p.PickWithInfo(&balancer.PickInfo{})
+}
+
+func (p *wrrPicker) PickWithInfo(info *balancer.PickInfo) (*balancer.Backend, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	var best *balancer.Backend
+	total := 0
+	p.backends.Range(func(index int, backend *balancer.Backend) bool {
+		if !backend.Available() || !info.Allow(backend) {
+			return true
+		}
+
+		weight := backend.Weight()
+		total += weight
+		p.current[backend.URL] += weight
+		if best == nil || p.current[backend.URL] > p.current[best.URL] {
+			best = backend
+		}
+		return true
+	})
+
+	if best == nil {
+		return nil, balancer.ErrNoBackendAvailable
+	}
+	p.current[best.URL] -= total
+
+	// remove the current weight of the deleted backends
+	if len(p.current) > p.backends.Len() {
+		for url := range p.current {
+			if _, ok := p.backends.Get(url); !ok {
+				delete(p.current, url)
+			}
+		}
+	}
+
+	return best, nil
+}
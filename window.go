@@ -0,0 +1,81 @@
+package balancer
+
+import (
+	"sync"
+	"time"
+)
+
+// SlidingWindow counts the successes and failures of the last period in buckets.
+type SlidingWindow struct {
+	width   int64
+	buckets []windowBucket
+	mux     sync.Mutex
+}
+
+type windowBucket struct {
+	start   int64
+	success uint64
+	failure uint64
+}
+
+// NewSlidingWindow creates a SlidingWindow of the size, divided into n buckets.
+func NewSlidingWindow(size time.Duration, n int) *SlidingWindow {
+	if n <= 0 {
+		n = 1
+	}
+	width := int64(size) / int64(n)
+	if width <= 0 {
+		width = 1
+	}
+	return &SlidingWindow{
+		width:   width,
+		buckets: make([]windowBucket, n),
+	}
+}
+
+// Add record a success or a failure
+func (w *SlidingWindow) Add(success bool) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	bucket := w.bucket(time.Now().UnixNano())
+	if success {
+		bucket.success++
+	} else {
+		bucket.failure++
+	}
+}
+
+// Counts return the successes and failures in the window
+func (w *SlidingWindow) Counts() (success, failure uint64) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	now := time.Now().UnixNano() / w.width
+	for _, bucket := range w.buckets {
+		if now-bucket.start < int64(len(w.buckets)) {
+			success += bucket.success
+			failure += bucket.failure
+		}
+	}
+	return success, failure
+}
+
+// Reset clear the window
+func (w *SlidingWindow) Reset() {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	for i := range w.buckets {
+		w.buckets[i] = windowBucket{}
+	}
+}
+
+func (w *SlidingWindow) bucket(now int64) *windowBucket {
+	start := now / w.width
+	bucket := &w.buckets[start%int64(len(w.buckets))]
+	if bucket.start != start {
+		*bucket = windowBucket{start: start}
+	}
+	return bucket
+}